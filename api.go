@@ -15,6 +15,8 @@
 package iam
 
 import (
+	"sync"
+
 	"github.com/sacloud/iam-api-go/apis/auth"
 	"github.com/sacloud/iam-api-go/apis/folder"
 	"github.com/sacloud/iam-api-go/apis/group"
@@ -31,6 +33,8 @@ import (
 	"github.com/sacloud/iam-api-go/apis/sso"
 	"github.com/sacloud/iam-api-go/apis/user"
 	"github.com/sacloud/iam-api-go/apis/user2fa"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
 )
 
 type AuthAPI = auth.AuthAPI
@@ -66,3 +70,146 @@ var NewServicePrincipalOp = serviceprincipal.NewServicePrincipalOp
 var NewSSOOp = sso.NewSSOOp
 var NewUserOp = user.NewUserOp
 var NewUser2FAOp = user2fa.NewUser2FAOp
+
+// ClientAPI gives access to every operation interface of the IAM API.
+// Depend on this rather than *Client to be able to swap in a fake.
+type ClientAPI interface {
+	Auth() AuthAPI
+	Folders() FolderAPI
+	Groups() GroupAPI
+	IAMPolicy() IAMPolicyAPI
+	IAMRoles() IAMRoleAPI
+	IDPolicy() IDPolicyAPI
+	IDRoles() IDRoleAPI
+	Organization() OrganizationAPI
+	Projects() ProjectAPI
+	ProjectAPIKeys() ProjectApiKeyAPI
+	Scim() ScimAPI
+	ServicePolicy() ServicePolicyAPI
+	ServicePrincipals() ServicePrincipalAPI
+	SSO() SSOAPI
+	Users() UserAPI
+	User2FA(user *v1.User) User2FAAPI
+}
+
+// Client is the ClientAPI backed by a single *v1.Client.  Operation
+// interfaces are created on first use and then reused.
+type Client struct {
+	client *v1.Client
+
+	auth              lazy[AuthAPI]
+	folders           lazy[FolderAPI]
+	groups            lazy[GroupAPI]
+	iamPolicy         lazy[IAMPolicyAPI]
+	iamRoles          lazy[IAMRoleAPI]
+	idPolicy          lazy[IDPolicyAPI]
+	idRoles           lazy[IDRoleAPI]
+	organization      lazy[OrganizationAPI]
+	projects          lazy[ProjectAPI]
+	projectAPIKeys    lazy[ProjectApiKeyAPI]
+	scim              lazy[ScimAPI]
+	servicePolicy     lazy[ServicePolicyAPI]
+	servicePrincipals lazy[ServicePrincipalAPI]
+	sso               lazy[SSOAPI]
+	users             lazy[UserAPI]
+}
+
+var _ ClientAPI = (*Client)(nil)
+
+// New creates a Client the same way NewClient creates a *v1.Client.
+func New(client saclient.ClientAPI, opts ...Option) (*Client, error) {
+	if c, err := NewClient(client, opts...); err != nil {
+		return nil, err
+	} else {
+		return FromV1(c), nil
+	}
+}
+
+// NewWithAPIRootURL creates a Client the same way NewClientWithAPIRootURL
+// creates a *v1.Client.
+func NewWithAPIRootURL(client saclient.ClientAPI, apiRootURL string, opts ...Option) (*Client, error) {
+	if c, err := NewClientWithAPIRootURL(client, apiRootURL, opts...); err != nil {
+		return nil, err
+	} else {
+		return FromV1(c), nil
+	}
+}
+
+// FromV1 wraps an already configured *v1.Client.
+func FromV1(client *v1.Client) *Client { return &Client{client: client} }
+
+// V1 returns the underlying generated client.
+func (c *Client) V1() *v1.Client { return c.client }
+
+func (c *Client) Auth() AuthAPI {
+	return c.auth.get(func() AuthAPI { return NewAuthOp(c.client) })
+}
+
+func (c *Client) Folders() FolderAPI {
+	return c.folders.get(func() FolderAPI { return NewFolderOp(c.client) })
+}
+
+func (c *Client) Groups() GroupAPI {
+	return c.groups.get(func() GroupAPI { return NewGroupOp(c.client) })
+}
+
+func (c *Client) IAMPolicy() IAMPolicyAPI {
+	return c.iamPolicy.get(func() IAMPolicyAPI { return NewIAMPolicyOp(c.client) })
+}
+
+func (c *Client) IAMRoles() IAMRoleAPI {
+	return c.iamRoles.get(func() IAMRoleAPI { return NewIAMRoleOp(c.client) })
+}
+
+func (c *Client) IDPolicy() IDPolicyAPI {
+	return c.idPolicy.get(func() IDPolicyAPI { return NewIDPolicyOp(c.client) })
+}
+
+func (c *Client) IDRoles() IDRoleAPI {
+	return c.idRoles.get(func() IDRoleAPI { return NewIDRoleOp(c.client) })
+}
+
+func (c *Client) Organization() OrganizationAPI {
+	return c.organization.get(func() OrganizationAPI { return NewOrganizationOp(c.client) })
+}
+
+func (c *Client) Projects() ProjectAPI {
+	return c.projects.get(func() ProjectAPI { return NewProjectOp(c.client) })
+}
+
+func (c *Client) ProjectAPIKeys() ProjectApiKeyAPI {
+	return c.projectAPIKeys.get(func() ProjectApiKeyAPI { return NewProjectAPIKeyOp(c.client) })
+}
+
+func (c *Client) Scim() ScimAPI {
+	return c.scim.get(func() ScimAPI { return NewScimOp(c.client) })
+}
+
+func (c *Client) ServicePolicy() ServicePolicyAPI {
+	return c.servicePolicy.get(func() ServicePolicyAPI { return NewServicePolicyOp(c.client) })
+}
+
+func (c *Client) ServicePrincipals() ServicePrincipalAPI {
+	return c.servicePrincipals.get(func() ServicePrincipalAPI { return NewServicePrincipalOp(c.client) })
+}
+
+func (c *Client) SSO() SSOAPI {
+	return c.sso.get(func() SSOAPI { return NewSSOOp(c.client) })
+}
+
+func (c *Client) Users() UserAPI {
+	return c.users.get(func() UserAPI { return NewUserOp(c.client) })
+}
+
+// User2FA is bound to a user, hence not cached.
+func (c *Client) User2FA(user *v1.User) User2FAAPI { return NewUser2FAOp(c.client, user) }
+
+type lazy[T any] struct {
+	once sync.Once
+	v    T
+}
+
+func (l *lazy[T]) get(f func() T) T {
+	l.once.Do(func() { l.v = f() })
+	return l.v
+}
@@ -16,6 +16,7 @@ package folder
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -111,3 +112,17 @@ func (f *folderOp) Move(ctx context.Context, ids []int, parent *int) error {
 
 	return err
 }
+
+// All iterates over every folder matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api FolderAPI, params ListParams) iter.Seq2[v1.Folder, error] {
+	return common.Paginate[v1.Folder](ctx, "Folder.All", params.Page, func(page int) (*v1.FoldersGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every folder matching params.
+func ListAll(ctx context.Context, api FolderAPI, params ListParams) ([]v1.Folder, error) {
+	return common.Collect(All(ctx, api, params))
+}
@@ -17,6 +17,7 @@ package group
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -125,3 +126,17 @@ func (g *groupOp) UpdateMemberships(ctx context.Context, groupID int, userIDs []
 		return ret.CompatUsers, nil
 	}
 }
+
+// All iterates over every group matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api GroupAPI, params ListParams) iter.Seq2[v1.Group, error] {
+	return common.Paginate[v1.Group](ctx, "Group.All", params.Page, func(page int) (*v1.GroupsGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every group matching params.
+func ListAll(ctx context.Context, api GroupAPI, params ListParams) ([]v1.Group, error) {
+	return common.Collect(All(ctx, api, params))
+}
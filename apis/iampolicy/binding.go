@@ -0,0 +1,120 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iampolicy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+type ScopeKind string
+
+const (
+	ScopeOrganization ScopeKind = "organization"
+	ScopeFolder       ScopeKind = "folder"
+	ScopeProject      ScopeKind = "project"
+)
+
+// Scope names the resource an IAM policy is attached to.  ID is ignored for
+// the organization.
+type Scope struct {
+	Kind ScopeKind
+	ID   int
+}
+
+func OrganizationScope() Scope  { return Scope{Kind: ScopeOrganization} }
+func FolderScope(id int) Scope  { return Scope{Kind: ScopeFolder, ID: id} }
+func ProjectScope(id int) Scope { return Scope{Kind: ScopeProject, ID: id} }
+
+func (s Scope) String() string {
+	if s.Kind == ScopeOrganization {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s %d", s.Kind, s.ID)
+}
+
+var errUnknownScope = errors.New("unknown policy scope")
+
+// ReadPolicy reads the policy of whatever scope points to.
+func ReadPolicy(ctx context.Context, api IAMPolicyAPI, scope Scope) ([]v1.IamPolicy, error) {
+	switch scope.Kind {
+	case ScopeOrganization:
+		return api.ReadOrganizationPolicy(ctx)
+	case ScopeFolder:
+		return api.ReadFolderPolicy(ctx, scope.ID)
+	case ScopeProject:
+		return api.ReadProjectPolicy(ctx, scope.ID)
+	default:
+		return nil, common.NewError("IamPolicy.ReadPolicy", errors.Wrap(errUnknownScope, string(scope.Kind)))
+	}
+}
+
+// UpdatePolicy replaces the policy of whatever scope points to.
+func UpdatePolicy(ctx context.Context, api IAMPolicyAPI, scope Scope, bindings []v1.IamPolicy) ([]v1.IamPolicy, error) {
+	switch scope.Kind {
+	case ScopeOrganization:
+		return api.UpdateOrganizationPolicy(ctx, bindings)
+	case ScopeFolder:
+		return api.UpdateFolderPolicy(ctx, scope.ID, bindings)
+	case ScopeProject:
+		return api.UpdateProjectPolicy(ctx, scope.ID, bindings)
+	default:
+		return nil, common.NewError("IamPolicy.UpdatePolicy", errors.Wrap(errUnknownScope, string(scope.Kind)))
+	}
+}
+
+// ModifyPolicy reads the policy, lets fn edit it and writes the result back.
+// fn may be called more than once: when the policy changes under our feet
+// the cycle is repeated with the fresh copy (see common.Modify).  fn gets its
+// own copy of the bindings and is free to modify it.
+//
+// Gives up with common.ErrConcurrentModification if the policy keeps
+// changing.
+func ModifyPolicy(ctx context.Context, api IAMPolicyAPI, scope Scope, fn func([]v1.IamPolicy) []v1.IamPolicy) ([]v1.IamPolicy, error) {
+	return common.Modify(ctx, "IamPolicy.ModifyPolicy",
+		func() ([]v1.IamPolicy, error) { return ReadPolicy(ctx, api, scope) },
+		func(b []v1.IamPolicy) ([]v1.IamPolicy, error) { return UpdatePolicy(ctx, api, scope, b) },
+		func(b []v1.IamPolicy) []v1.IamPolicy { return fn(common.CloneBindings(b)) },
+	)
+}
+
+// AddBinding grants role to principals on scope.  Principals that already
+// hold the role are left alone, and the entries of the role are merged into
+// one without duplicate principals.
+func AddBinding(ctx context.Context, api IAMPolicyAPI, scope Scope, role string, principals ...v1.Principal) ([]v1.IamPolicy, error) {
+	return ModifyPolicy(ctx, api, scope, func(b []v1.IamPolicy) []v1.IamPolicy {
+		return common.GrantRole(b, role, principals...)
+	})
+}
+
+// RemoveBinding revokes role from principals on scope.  A binding left
+// without principals is dropped altogether.
+func RemoveBinding(ctx context.Context, api IAMPolicyAPI, scope Scope, role string, principals ...v1.Principal) ([]v1.IamPolicy, error) {
+	return ModifyPolicy(ctx, api, scope, func(b []v1.IamPolicy) []v1.IamPolicy {
+		return common.RevokeRole(b, role, principals...)
+	})
+}
+
+// SetBindingsForRole makes principals the only holders of role on scope.
+// Other roles are left alone; no principals at all removes the binding.
+func SetBindingsForRole(ctx context.Context, api IAMPolicyAPI, scope Scope, role string, principals ...v1.Principal) ([]v1.IamPolicy, error) {
+	return ModifyPolicy(ctx, api, scope, func(b []v1.IamPolicy) []v1.IamPolicy {
+		return common.SetRole(b, role, principals...)
+	})
+}
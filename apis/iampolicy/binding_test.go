@@ -0,0 +1,167 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iampolicy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	. "github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFake returns an API backed by the fake server, a project and two users.
+func setupFake(t *testing.T) (*require.Assertions, IAMPolicyAPI, Scope, v1.Principal, v1.Principal) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	u1, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "u1", Code: "u1", Password: "password"})
+	assert.NoError(err)
+	u2, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "u2", Code: "u2", Password: "password"})
+	assert.NoError(err)
+
+	return assert, NewIAMPolicyOp(client), ProjectScope(p.ID), iam.UserPrincipal(u1.ID), iam.UserPrincipal(u2.ID)
+}
+
+func TestAddBinding(t *testing.T) {
+	assert, api, scope, u1, u2 := setupFake(t)
+
+	_, err := AddBinding(t.Context(), api, scope, "admin", u1)
+	assert.NoError(err)
+	actual, err := AddBinding(t.Context(), api, scope, "admin", u1, u2)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", u1, u2)}, actual)
+
+	actual, err = ReadPolicy(t.Context(), api, scope)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", u1, u2)}, actual)
+}
+
+func TestAddBinding_Normalizes(t *testing.T) {
+	assert, api, _, u1, u2 := setupFake(t)
+	scope := OrganizationScope()
+	_, err := UpdatePolicy(t.Context(), api, scope, []v1.IamPolicy{
+		iam.IAMBinding("organization-admin", u1, u1),
+		iam.IAMBinding("owner", u2),
+		iam.IAMBinding("organization-admin", u1),
+	})
+	assert.NoError(err)
+
+	actual, err := AddBinding(t.Context(), api, scope, "organization-admin", u2)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{
+		iam.IAMBinding("organization-admin", u1, u2),
+		iam.IAMBinding("owner", u2),
+	}, actual)
+}
+
+func TestRemoveBinding(t *testing.T) {
+	assert, api, scope, u1, u2 := setupFake(t)
+	_, err := UpdatePolicy(t.Context(), api, scope, []v1.IamPolicy{iam.IAMBinding("admin", u1, u2)})
+	assert.NoError(err)
+
+	actual, err := RemoveBinding(t.Context(), api, scope, "admin", u1)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", u2)}, actual)
+
+	actual, err = RemoveBinding(t.Context(), api, scope, "admin", u2)
+	assert.NoError(err)
+	assert.Empty(actual)
+
+	actual, err = ReadPolicy(t.Context(), api, scope)
+	assert.NoError(err)
+	assert.Empty(actual)
+}
+
+func TestSetBindingsForRole(t *testing.T) {
+	assert, api, _, u1, u2 := setupFake(t)
+	scope := OrganizationScope()
+	_, err := UpdatePolicy(t.Context(), api, scope, []v1.IamPolicy{
+		iam.IAMBinding("owner", u1),
+		iam.IAMBinding("organization-admin", u1),
+	})
+	assert.NoError(err)
+
+	actual, err := SetBindingsForRole(t.Context(), api, scope, "organization-admin", u2, u2)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{
+		iam.IAMBinding("owner", u1),
+		iam.IAMBinding("organization-admin", u2),
+	}, actual)
+
+	actual, err = SetBindingsForRole(t.Context(), api, scope, "organization-admin")
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("owner", u1)}, actual)
+}
+
+// racing changes the policy behind the caller's back on the second read.
+type racing struct {
+	IAMPolicyAPI
+	reads   int
+	meddler v1.Principal
+}
+
+func (r *racing) ReadProjectPolicy(ctx context.Context, id int) ([]v1.IamPolicy, error) {
+	if r.reads++; r.reads == 2 {
+		b := []v1.IamPolicy{iam.IAMBinding("admin", r.meddler)}
+		if _, err := r.UpdateProjectPolicy(ctx, id, b); err != nil {
+			return nil, err
+		}
+	}
+	return r.IAMPolicyAPI.ReadProjectPolicy(ctx, id)
+}
+
+func TestModifyPolicy_Concurrent(t *testing.T) {
+	assert, api, scope, u1, u2 := setupFake(t)
+	api = &racing{IAMPolicyAPI: api, meddler: u2}
+
+	actual, err := AddBinding(t.Context(), api, scope, "admin", u1)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", u2, u1)}, actual)
+}
+
+func TestModifyPolicy_DoesNotAlias(t *testing.T) {
+	assert, api, scope, u1, u2 := setupFake(t)
+	_, err := AddBinding(t.Context(), api, scope, "admin", u1)
+	assert.NoError(err)
+
+	var seen []v1.IamPolicy
+	_, err = ModifyPolicy(t.Context(), api, scope, func(b []v1.IamPolicy) []v1.IamPolicy {
+		seen = b
+		b[0].Principals[0] = u2
+		return b
+	})
+	assert.NoError(err)
+	seen[0].Principals[0] = u1
+
+	actual, err := ReadPolicy(t.Context(), api, scope)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", u2)}, actual)
+}
+
+func TestReadPolicy_UnknownScope(t *testing.T) {
+	assert, api := setup(t, make(map[string]any))
+
+	_, err := ReadPolicy(t.Context(), api, Scope{Kind: "zone"})
+	assert.Error(err)
+}
@@ -16,6 +16,7 @@ package iamrole
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -46,3 +47,16 @@ func (i *iamRoleOp) Read(ctx context.Context, id string) (*v1.IamRole, error) {
 		return i.client.IamRolesIamRoleIDGet(ctx, v1.IamRolesIamRoleIDGetParams{IamRoleID: id})
 	})
 }
+
+// All iterates over every IAM role, fetching pages on demand.
+// page, if set, is the page to start from.
+func All(ctx context.Context, api IAMRoleAPI, page, perPage *int) iter.Seq2[v1.IamRole, error] {
+	return common.Paginate[v1.IamRole](ctx, "IAMRole.All", page, func(page int) (*v1.IamRolesGetOK, error) {
+		return api.List(ctx, &page, perPage)
+	})
+}
+
+// ListAll collects every IAM role.
+func ListAll(ctx context.Context, api IAMRoleAPI, page, perPage *int) ([]v1.IamRole, error) {
+	return common.Collect(All(ctx, api, page, perPage))
+}
@@ -63,6 +63,32 @@ func TestList_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestListAll(t *testing.T) {
+	var expected v1.IamRolesGetOK
+	expected.SetFake()
+	expected.SetItems(make([]v1.IamRole, 2))
+	expected.Items[0].SetFake()
+	expected.Items[1].SetFake()
+	expected.SetCount(2)
+	assert, api := setup(t, &expected)
+
+	actual, err := ListAll(t.Context(), api, nil, nil)
+	assert.NoError(err)
+	assert.Equal(expected.GetItems(), actual)
+}
+
+func TestListAll_Fail(t *testing.T) {
+	var res v1.Http403Forbidden
+	res.SetFake()
+	res.SetStatus(http.StatusForbidden)
+	assert, api := setup(t, &res, res.Status)
+
+	for actual, err := range All(t.Context(), api, nil, nil) {
+		assert.Error(err)
+		assert.Zero(actual)
+	}
+}
+
 func TestGet(t *testing.T) {
 	var expected v1.IamRole
 	expected.SetFake()
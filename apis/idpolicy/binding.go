@@ -0,0 +1,61 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idpolicy
+
+import (
+	"context"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// ModifyPolicy reads the organization ID policy, lets fn edit it and writes
+// the result back, starting over when the policy changes under our feet (see
+// common.Modify).  fn gets its own copy of the bindings.
+//
+// Gives up with common.ErrConcurrentModification if the policy keeps
+// changing.
+func ModifyPolicy(ctx context.Context, api IDPolicyAPI, fn func([]v1.IdPolicy) []v1.IdPolicy) ([]v1.IdPolicy, error) {
+	return common.Modify(ctx, "IdPolicy.ModifyPolicy",
+		func() ([]v1.IdPolicy, error) { return api.ReadOrganizationIdPolicy(ctx) },
+		func(b []v1.IdPolicy) ([]v1.IdPolicy, error) { return api.UpdateOrganizationIdPolicy(ctx, b) },
+		func(b []v1.IdPolicy) []v1.IdPolicy { return fn(common.CloneBindings(b)) },
+	)
+}
+
+// AddBinding grants the ID role to principals.  Principals that already hold
+// the role are left alone, and the entries of the role are merged into one
+// without duplicate principals.
+func AddBinding(ctx context.Context, api IDPolicyAPI, role string, principals ...v1.Principal) ([]v1.IdPolicy, error) {
+	return ModifyPolicy(ctx, api, func(b []v1.IdPolicy) []v1.IdPolicy {
+		return common.GrantRole(b, role, principals...)
+	})
+}
+
+// RemoveBinding revokes the ID role from principals.  A binding left without
+// principals is dropped altogether.
+func RemoveBinding(ctx context.Context, api IDPolicyAPI, role string, principals ...v1.Principal) ([]v1.IdPolicy, error) {
+	return ModifyPolicy(ctx, api, func(b []v1.IdPolicy) []v1.IdPolicy {
+		return common.RevokeRole(b, role, principals...)
+	})
+}
+
+// SetBindingsForRole makes principals the only holders of the ID role.
+// Other roles are left alone; no principals at all removes the binding.
+func SetBindingsForRole(ctx context.Context, api IDPolicyAPI, role string, principals ...v1.Principal) ([]v1.IdPolicy, error) {
+	return ModifyPolicy(ctx, api, func(b []v1.IdPolicy) []v1.IdPolicy {
+		return common.SetRole(b, role, principals...)
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idpolicy_test
+
+import (
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	. "github.com/sacloud/iam-api-go/apis/idpolicy"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// setupFake returns an API backed by the fake server, a user and a service
+// principal.  The server knows an extra "auditor" ID role.
+func setupFake(t *testing.T) (*require.Assertions, IDPolicyAPI, v1.Principal, v1.Principal) {
+	assert := require.New(t)
+	client, sv := iam_test.NewFakeClient(t)
+	sv.AddIDRole(v1.IdRole{ID: "auditor", Name: "auditor"})
+	ctx := t.Context()
+
+	u, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "u", Code: "u", Password: "password"})
+	assert.NoError(err)
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	sp, err := iam.NewServicePrincipalOp(client).Create(ctx, serviceprincipal.CreateParams{ProjectID: p.ID, Name: "sp"})
+	assert.NoError(err)
+
+	return assert, NewIDPolicyOp(client), iam.UserPrincipal(u.ID), iam.ServicePrincipalPrincipal(sp.ID)
+}
+
+func TestAddBinding(t *testing.T) {
+	assert, api, u, sp := setupFake(t)
+	auditors := iam.IDBinding("auditor", u)
+	_, err := api.UpdateOrganizationIdPolicy(t.Context(), []v1.IdPolicy{auditors})
+	assert.NoError(err)
+
+	_, err = AddBinding(t.Context(), api, "identity-admin", u)
+	assert.NoError(err)
+	actual, err := AddBinding(t.Context(), api, "identity-admin", sp, u)
+	assert.NoError(err)
+	assert.Equal([]v1.IdPolicy{
+		auditors,
+		iam.IDBinding("identity-admin", u, sp),
+	}, actual)
+
+	actual, err = api.ReadOrganizationIdPolicy(t.Context())
+	assert.NoError(err)
+	assert.Len(actual, 2)
+}
+
+func TestAddBinding_Normalizes(t *testing.T) {
+	assert, api, u, sp := setupFake(t)
+	_, err := api.UpdateOrganizationIdPolicy(t.Context(), []v1.IdPolicy{
+		iam.IDBinding("identity-admin", u, u),
+		iam.IDBinding("auditor", sp),
+		iam.IDBinding("identity-admin", u),
+	})
+	assert.NoError(err)
+
+	actual, err := AddBinding(t.Context(), api, "identity-admin", sp)
+	assert.NoError(err)
+	assert.Equal([]v1.IdPolicy{
+		iam.IDBinding("identity-admin", u, sp),
+		iam.IDBinding("auditor", sp),
+	}, actual)
+}
+
+func TestRemoveBinding(t *testing.T) {
+	assert, api, u, sp := setupFake(t)
+	_, err := api.UpdateOrganizationIdPolicy(t.Context(), []v1.IdPolicy{
+		iam.IDBinding("auditor", u),
+		iam.IDBinding("identity-admin", u, sp),
+	})
+	assert.NoError(err)
+
+	actual, err := RemoveBinding(t.Context(), api, "identity-admin", u)
+	assert.NoError(err)
+	assert.Equal([]v1.IdPolicy{
+		iam.IDBinding("auditor", u),
+		iam.IDBinding("identity-admin", sp),
+	}, actual)
+
+	actual, err = RemoveBinding(t.Context(), api, "auditor", u, sp)
+	assert.NoError(err)
+	assert.Equal([]v1.IdPolicy{iam.IDBinding("identity-admin", sp)}, actual)
+}
+
+func TestSetBindingsForRole(t *testing.T) {
+	assert, api, u, sp := setupFake(t)
+	_, err := AddBinding(t.Context(), api, "identity-admin", u)
+	assert.NoError(err)
+
+	actual, err := SetBindingsForRole(t.Context(), api, "identity-admin", sp)
+	assert.NoError(err)
+	assert.Equal([]v1.IdPolicy{iam.IDBinding("identity-admin", sp)}, actual)
+
+	actual, err = SetBindingsForRole(t.Context(), api, "identity-admin")
+	assert.NoError(err)
+	assert.Empty(actual)
+}
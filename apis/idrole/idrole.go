@@ -16,6 +16,7 @@ package idrole
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -46,3 +47,16 @@ func (i *idRoleOp) Read(ctx context.Context, id string) (*v1.IdRole, error) {
 		return i.client.IDRolesIDRoleIDGet(ctx, v1.IDRolesIDRoleIDGetParams{IDRoleID: id})
 	})
 }
+
+// All iterates over every ID role, fetching pages on demand.
+// page, if set, is the page to start from.
+func All(ctx context.Context, api IDRoleAPI, page, perPage *int) iter.Seq2[v1.IdRole, error] {
+	return common.Paginate[v1.IdRole](ctx, "IdRole.All", page, func(page int) (*v1.IDRolesGetOK, error) {
+		return api.List(ctx, &page, perPage)
+	})
+}
+
+// ListAll collects every ID role.
+func ListAll(ctx context.Context, api IDRoleAPI, page, perPage *int) ([]v1.IdRole, error) {
+	return common.Collect(All(ctx, api, page, perPage))
+}
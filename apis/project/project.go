@@ -16,6 +16,7 @@ package project
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -120,3 +121,17 @@ func (p *projectOp) Move(ctx context.Context, ids []int, parentFolderID *int) er
 
 	return err
 }
+
+// All iterates over every project matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api ProjectAPI, params ListParams) iter.Seq2[v1.Project, error] {
+	return common.Paginate[v1.Project](ctx, "Project.All", params.Page, func(page int) (*v1.ProjectsGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every project matching params.
+func ListAll(ctx context.Context, api ProjectAPI, params ListParams) ([]v1.Project, error) {
+	return common.Collect(All(ctx, api, params))
+}
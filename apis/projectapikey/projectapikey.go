@@ -16,6 +16,7 @@ package projectapikey
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -112,3 +113,17 @@ func (p *projectApiKeyOp) Delete(ctx context.Context, id int) error {
 
 	return err
 }
+
+// All iterates over every project API key matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api ProjectAPIKeyAPI, params ListParams) iter.Seq2[v1.ProjectApiKey, error] {
+	return common.Paginate[v1.ProjectApiKey](ctx, "ProjectAPIKey.All", params.Page, func(page int) (*v1.CompatAPIKeysGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every project API key matching params.
+func ListAll(ctx context.Context, api ProjectAPIKeyAPI, params ListParams) ([]v1.ProjectApiKey, error) {
+	return common.Collect(All(ctx, api, params))
+}
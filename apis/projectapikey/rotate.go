@@ -0,0 +1,71 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectapikey
+
+import (
+	"context"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+type RotateOptions struct {
+	// Confirm, if set, is called with the new key once it exists, to deploy
+	// its secret for instance.  The old key is deleted once it returns nil,
+	// and kept if it fails.  Without Confirm the old key is kept.
+	Confirm func(ctx context.Context, key *v1.ProjectApiKeyWithSecret) error
+}
+
+// Rotate creates a copy of the API key id, with the same project, name,
+// description, roles, server and zone, and returns it with its secret.
+//
+// The new key is returned even when Confirm or deleting the old key fails,
+// along with the error, as its secret cannot be read again.
+func Rotate(ctx context.Context, api ProjectAPIKeyAPI, id int, opts RotateOptions) (*v1.ProjectApiKeyWithSecret, error) {
+	old, err := api.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := api.Create(ctx, CreateParams{
+		ProjectID:        old.ProjectID,
+		Name:             old.Name,
+		Description:      old.Description,
+		ServerResourceID: optional(old.ServerResourceID),
+		IamRoles:         old.IamRoles,
+		Zone:             optional(old.ZoneID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Confirm == nil {
+		return key, nil
+	}
+	if err := opts.Confirm(ctx, key); err != nil {
+		return key, common.NewError("ProjectAPIKey.Rotate", errors.Wrapf(err, "keeping API key %d", id))
+	}
+	if err := api.Delete(ctx, id); err != nil {
+		return key, common.NewError("ProjectAPIKey.Rotate", errors.Wrapf(err, "deleting API key %d", id))
+	}
+	return key, nil
+}
+
+func optional(o v1.OptNilString) *string {
+	if v, ok := o.Get(); ok {
+		return &v
+	}
+	return nil
+}
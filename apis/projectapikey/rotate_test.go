@@ -0,0 +1,115 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package projectapikey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/project"
+	. "github.com/sacloud/iam-api-go/apis/projectapikey"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRotate(t *testing.T) (*require.Assertions, ProjectAPIKeyAPI, *v1.ProjectApiKeyWithSecret) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "web", Name: "web"})
+	assert.NoError(err)
+	api := NewProjectAPIKeyOp(client)
+	server, zone := "113000000000", "is1a"
+	old, err := api.Create(ctx, CreateParams{
+		ProjectID:        p.ID,
+		Name:             "ci",
+		Description:      "deploys web",
+		ServerResourceID: &server,
+		IamRoles:         []string{"admin"},
+		Zone:             &zone,
+	})
+	assert.NoError(err)
+	return assert, api, old
+}
+
+func TestRotate(t *testing.T) {
+	assert, api, old := setupRotate(t)
+	ctx := t.Context()
+
+	var confirmed *v1.ProjectApiKeyWithSecret
+	key, err := Rotate(ctx, api, old.ID, RotateOptions{
+		Confirm: func(_ context.Context, k *v1.ProjectApiKeyWithSecret) error {
+			confirmed = k
+			return nil
+		},
+	})
+	assert.NoError(err)
+	assert.Equal(key, confirmed)
+	assert.NotEqual(old.ID, key.ID)
+	assert.NotEmpty(key.AccessTokenSecret)
+	assert.NotEqual(old.AccessTokenSecret, key.AccessTokenSecret)
+	for _, i := range []func(*v1.ProjectApiKeyWithSecret) any{
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.ProjectID },
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.Name },
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.Description },
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.ServerResourceID },
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.IamRoles },
+		func(k *v1.ProjectApiKeyWithSecret) any { return k.ZoneID },
+	} {
+		assert.Equal(i(old), i(key))
+	}
+
+	_, err = api.Read(ctx, old.ID)
+	assert.Error(err)
+}
+
+func TestRotate_Keep(t *testing.T) {
+	assert, api, old := setupRotate(t)
+	ctx := t.Context()
+
+	key, err := Rotate(ctx, api, old.ID, RotateOptions{})
+	assert.NoError(err)
+	_, err = api.Read(ctx, old.ID)
+	assert.NoError(err)
+
+	key2, err := Rotate(ctx, api, key.ID, RotateOptions{
+		Confirm: func(context.Context, *v1.ProjectApiKeyWithSecret) error { return errors.New("rollout failed") },
+	})
+	assert.ErrorContains(err, "rollout failed")
+	assert.NotNil(key2)
+	_, err = api.Read(ctx, key.ID)
+	assert.NoError(err)
+	_, err = api.Read(ctx, key2.ID)
+	assert.NoError(err)
+}
+
+func TestRotate_DeleteFails(t *testing.T) {
+	assert, api, old := setupRotate(t)
+	ctx := t.Context()
+
+	// gone by the time Rotate deletes it
+	key, err := Rotate(ctx, api, old.ID, RotateOptions{
+		Confirm: func(ctx context.Context, _ *v1.ProjectApiKeyWithSecret) error { return api.Delete(ctx, old.ID) },
+	})
+	assert.ErrorContains(err, fmt.Sprintf("ProjectAPIKey.Rotate: deleting API key %d", old.ID))
+	assert.NotNil(key)
+	_, err = api.Read(ctx, key.ID)
+	assert.NoError(err)
+}
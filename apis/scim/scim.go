@@ -16,6 +16,7 @@ package scim
 
 import (
 	"context"
+	"iter"
 
 	"github.com/google/uuid"
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
@@ -137,3 +138,17 @@ func (s *scimOp) RegenerateToken(ctx context.Context, id string) (*v1.ScimConfig
 		})
 	})
 }
+
+// All iterates over every SCIM configuration matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api ScimAPI, params ListParams) iter.Seq2[v1.ScimConfigurationBase, error] {
+	return common.Paginate[v1.ScimConfigurationBase](ctx, "Scim.All", params.Page, func(page int) (*v1.ScimConfigurationsGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every SCIM configuration matching params.
+func ListAll(ctx context.Context, api ScimAPI, params ListParams) ([]v1.ScimConfigurationBase, error) {
+	return common.Collect(All(ctx, api, params))
+}
@@ -16,6 +16,7 @@ package servicepolicy
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -79,3 +80,17 @@ func (s *servicePolicyOp) ListRuleTemplates(ctx context.Context, params ListRule
 		})
 	})
 }
+
+// AllRuleTemplates iterates over every rule template matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func AllRuleTemplates(ctx context.Context, api ServicePolicyAPI, params ListRuleTemplatesParams) iter.Seq2[v1.RuleTemplate, error] {
+	return common.Paginate[v1.RuleTemplate](ctx, "ServicePolicy.AllRuleTemplates", params.Page, func(page int) (*v1.ServicePolicyRuleTemplatesGetOK, error) {
+		params.Page = &page
+		return api.ListRuleTemplates(ctx, params)
+	})
+}
+
+// ListAllRuleTemplates collects every rule template matching params.
+func ListAllRuleTemplates(ctx context.Context, api ServicePolicyAPI, params ListRuleTemplatesParams) ([]v1.RuleTemplate, error) {
+	return common.Collect(AllRuleTemplates(ctx, api, params))
+}
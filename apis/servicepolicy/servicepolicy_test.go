@@ -133,6 +133,32 @@ func TestGetRuleTemplates_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestListAllRuleTemplates(t *testing.T) {
+	var expected v1.ServicePolicyRuleTemplatesGetOK
+	expected.SetFake()
+	expected.SetItems(make([]v1.RuleTemplate, 2))
+	expected.Items[0].SetFake()
+	expected.Items[1].SetFake()
+	expected.SetCount(2)
+	assert, api := setup(t, &expected)
+
+	actual, err := ListAllRuleTemplates(t.Context(), api, ListRuleTemplatesParams{})
+	assert.NoError(err)
+	assert.Equal(expected.GetItems(), actual)
+}
+
+func TestListAllRuleTemplates_Fail(t *testing.T) {
+	var res v1.Http403Forbidden
+	res.SetFake()
+	res.SetStatus(http.StatusForbidden)
+	assert, api := setup(t, &res, res.Status)
+
+	for actual, err := range AllRuleTemplates(t.Context(), api, ListRuleTemplatesParams{}) {
+		assert.Error(err)
+		assert.Zero(actual)
+	}
+}
+
 func TestIntegrated(t *testing.T) {
 	assert, client := iam_test.IntegratedClient(t)
 	op := NewServicePolicyOp(client)
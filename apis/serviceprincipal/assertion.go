@@ -0,0 +1,149 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strconv"
+	"time"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// TokenAudience is the "aud" the production endpoint expects in an
+// assertion.
+const TokenAudience = "https://secure.sakura.ad.jp/cloud/api/iam/1.0/service-principals/oauth2/token"
+
+// AssertionLifetime is how long an assertion is valid by default.
+const AssertionLifetime = 5 * time.Minute
+
+type AssertionParams struct {
+	ServicePrincipalID int
+	Kid                string // of the ServicePrincipalKey whose private half Key is
+
+	// Signs with RS256, the only algorithm IssueToken accepts.  Anything
+	// implementing crypto.Signer with an RSA public key will do, a key held
+	// in an HSM for instance.
+	Key crypto.Signer
+
+	// Defaults to TokenAudience, which is not derived from the endpoint the
+	// client talks to: set it when issuing tokens anywhere but production.
+	Audience string
+
+	// Default to AssertionLifetime and now.
+	Lifetime time.Duration
+	IssuedAt time.Time
+}
+
+// BuildAssertion signs the JWT that IssueToken exchanges for an access
+// token.
+func BuildAssertion(params AssertionParams) (string, error) {
+	if err := checkKey(params.Key); err != nil {
+		return "", common.NewError("ServicePrincipal.BuildAssertion", err)
+	}
+	if params.Audience == "" {
+		params.Audience = TokenAudience
+	}
+	if params.Lifetime == 0 {
+		params.Lifetime = AssertionLifetime
+	}
+	if params.IssuedAt.IsZero() {
+		params.IssuedAt = time.Now()
+	}
+
+	sub := strconv.Itoa(params.ServicePrincipalID)
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": params.Kid, "typ": "JWT"})
+	if err != nil {
+		return "", common.NewError("ServicePrincipal.BuildAssertion", err)
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": params.Audience,
+		"exp": params.IssuedAt.Add(params.Lifetime).Unix(),
+		"iat": params.IssuedAt.Unix(),
+		"iss": sub,
+		"sub": sub,
+	})
+	if err != nil {
+		return "", common.NewError("ServicePrincipal.BuildAssertion", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := params.Key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return "", common.NewError("ServicePrincipal.BuildAssertion", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// IssueTokenWithKey builds an assertion from params and exchanges it for an
+// access token.
+func IssueTokenWithKey(ctx context.Context, api ServicePrincipalAPI, params AssertionParams) (*v1.ServicePrincipalOAuth2AccessToken, error) {
+	assertion, err := BuildAssertion(params)
+	if err != nil {
+		return nil, err
+	}
+	return api.IssueToken(ctx, assertion)
+}
+
+// ParsePrivateKey reads a PEM encoded RSA private key, in PKCS #1 or PKCS #8
+// form.
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, common.NewError("ServicePrincipal.ParsePrivateKey", errors.New("no PEM block found"))
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = errors.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, common.NewError("ServicePrincipal.ParsePrivateKey", err)
+	}
+
+	k, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, common.NewError("ServicePrincipal.ParsePrivateKey", errors.Errorf("unsupported key type %T", key))
+	}
+	return k, nil
+}
+
+// only RSA keys can sign an assertion
+func checkKey(key crypto.Signer) error {
+	if key == nil {
+		return errors.New("no key")
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); !ok {
+		return errors.Errorf("unsupported key type %T", key.Public())
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sacloud/iam-api-go/apis/project"
+	. "github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, assertion string, key crypto.PublicKey, alg string) (*jwt.Token, jwt.MapClaims) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (any, error) { return key, nil },
+		jwt.WithValidMethods([]string{alg}), jwt.WithAudience(TokenAudience), jwt.WithIssuedAt())
+	require.NoError(t, err)
+	return token, claims
+}
+
+func TestBuildAssertion_RSA(t *testing.T) {
+	assert := require.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+
+	now := time.Now().Truncate(time.Second)
+	s, err := BuildAssertion(AssertionParams{ServicePrincipalID: 123, Kid: "kid", Key: key, IssuedAt: now})
+	assert.NoError(err)
+
+	token, claims := parse(t, s, &key.PublicKey, "RS256")
+	assert.Equal("kid", token.Header["kid"])
+	assert.Equal("JWT", token.Header["typ"])
+	assert.Equal("123", claims["iss"])
+	assert.Equal("123", claims["sub"])
+	exp, err := claims.GetExpirationTime()
+	assert.NoError(err)
+	assert.Equal(now.Add(AssertionLifetime), exp.Time)
+}
+
+func TestBuildAssertion_Unsupported(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	for _, key := range []crypto.Signer{edKey, ecKey} {
+		_, err = BuildAssertion(AssertionParams{ServicePrincipalID: 1, Kid: "kid", Key: key})
+		require.ErrorContains(t, err, "unsupported key type")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	assert := require.New(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	assert.NoError(err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	assert.NoError(err)
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(err)
+
+	for typ, der := range map[string][]byte{
+		"RSA PRIVATE KEY": x509.MarshalPKCS1PrivateKey(rsaKey),
+		"PRIVATE KEY":     pkcs8,
+	} {
+		key, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+		assert.NoError(err, typ)
+		assert.NotNil(key.Public(), typ)
+	}
+
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	assert.Error(err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8}))
+	assert.ErrorContains(err, "unsupported key type")
+	_, err = ParsePrivateKey([]byte("garbage"))
+	assert.Error(err)
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{0}}))
+	assert.Error(err)
+}
+
+func TestIssueTokenWithKey(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	api := NewServicePrincipalOp(client)
+
+	p, err := project.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	sp, err := api.Create(ctx, CreateParams{ProjectID: p.ID, Name: "sp"})
+	assert.NoError(err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NoError(err)
+	k, err := api.UploadKey(ctx, sp.ID, v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	assert.NoError(err)
+
+	token, err := IssueTokenWithKey(ctx, api, AssertionParams{ServicePrincipalID: sp.ID, Kid: k.Kid, Key: key})
+	assert.NoError(err)
+	assert.NotEmpty(token.AccessToken)
+
+	_, err = IssueTokenWithKey(ctx, api, AssertionParams{ServicePrincipalID: sp.ID, Kid: "wrong", Key: key})
+	assert.Error(err)
+}
@@ -0,0 +1,142 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// JWK is a public key in the JSON Web Key format of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+
+	// the RSA modulus and exponent
+	N string `json:"n"`
+	E string `json:"e"`
+}
+
+// JWKSet is a JWK Set, as served at a jwks_uri.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Key returns the key with the given kid.
+func (s *JWKSet) Key(kid string) (JWK, bool) {
+	for _, k := range s.Keys {
+		if k.Kid == kid {
+			return k, true
+		}
+	}
+	return JWK{}, false
+}
+
+// ServeHTTP serves s as application/jwk-set+json.
+func (s *JWKSet) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// PublicJWK converts the public half of k, as uploaded, to a JWK.  Only RSA
+// keys, the kind the API documents, are supported.
+func PublicJWK(k v1.ServicePrincipalKey) (JWK, error) {
+	block, _ := pem.Decode([]byte(k.PublicKey))
+	if block == nil {
+		return JWK{}, common.NewError("ServicePrincipal.PublicJWK", errors.Errorf("key %s: no PEM block found", k.Kid))
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		err = errors.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return JWK{}, common.NewError("ServicePrincipal.PublicJWK", errors.Wrapf(err, "key %s", k.Kid))
+	}
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return JWK{}, common.NewError("ServicePrincipal.PublicJWK", errors.Errorf("key %s: unsupported key type %T", k.Kid, key))
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: k.Kid,
+		N:   b64(pub.N.Bytes()),
+		E:   b64(big.NewInt(int64(pub.E)).Bytes()),
+	}, nil
+}
+
+// ExportJWKS lists the keys of the service principals ids and returns the
+// enabled ones that have not expired as a JWK Set.
+//
+// A key that cannot be converted, say of an unsupported type, is left out
+// rather than withholding the others: the set then comes with an error
+// listing such keys.  Failing to list the keys returns no set.
+func ExportJWKS(ctx context.Context, api ServicePrincipalAPI, ids ...int) (*JWKSet, error) {
+	now := time.Now()
+	ret := JWKSet{Keys: []JWK{}}
+	var skipped []error
+	for _, id := range ids {
+		keys, err := ListAllKeys(ctx, api, id, ListKeysParams{})
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			if k.Status != v1.ServicePrincipalKeyStatusEnabled || expired(k, now) {
+				continue
+			}
+			jwk, err := PublicJWK(k)
+			if err != nil {
+				skipped = append(skipped, err)
+				continue
+			}
+			ret.Keys = append(ret.Keys, jwk)
+		}
+	}
+	if len(skipped) > 0 {
+		return &ret, common.NewError("ServicePrincipal.ExportJWKS", errors.Join(skipped...))
+	}
+	return &ret, nil
+}
+
+// whether k expired by now; an expiry that cannot be read is taken for none
+func expired(k v1.ServicePrincipalKey, now time.Time) bool {
+	s, ok := k.KeyExpiresAt.Get()
+	if !ok {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return err == nil && !now.Before(t)
+}
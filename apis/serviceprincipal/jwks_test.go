@@ -0,0 +1,126 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sacloud/iam-api-go/apis/project"
+	. "github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, s string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	return new(big.Int).SetBytes(b)
+}
+
+func TestExportJWKS(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	api := NewServicePrincipalOp(client)
+
+	p, err := project.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	var ids []int
+	var keys []*ProvisionedKey
+	for _, name := range []string{"a", "b"} {
+		sp, err := api.Create(ctx, CreateParams{ProjectID: p.ID, Name: name})
+		assert.NoError(err)
+		k, err := ProvisionKey(ctx, api, sp.ID, KeyOptions{})
+		assert.NoError(err)
+		ids, keys = append(ids, sp.ID), append(keys, k)
+	}
+	disabled, err := ProvisionKey(ctx, api, ids[0], KeyOptions{})
+	assert.NoError(err)
+	_, err = api.DisableKey(ctx, ids[0], disabled.Key.ID)
+	assert.NoError(err)
+
+	set, err := ExportJWKS(ctx, api, ids...)
+	assert.NoError(err)
+	assert.Len(set.Keys, 2)
+	_, ok := set.Key(disabled.Key.Kid)
+	assert.False(ok)
+
+	for _, k := range keys {
+		jwk, ok := set.Key(k.Key.Kid)
+		assert.True(ok)
+		assert.Equal("RSA", jwk.Kty)
+		assert.Equal("RS256", jwk.Alg)
+		assert.Equal("sig", jwk.Use)
+		pub := rsa.PublicKey{N: decode(t, jwk.N), E: int(decode(t, jwk.E).Int64())}
+		assert.True(pub.Equal(k.Signer.Public()))
+	}
+
+	w := httptest.NewRecorder()
+	set.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jwks.json", nil))
+	assert.Equal("application/jwk-set+json", w.Header().Get("Content-Type"))
+	var served JWKSet
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(*set, served)
+}
+
+// lists the same keys for every service principal
+type keyLister struct {
+	ServicePrincipalAPI
+	keys []v1.ServicePrincipalKey
+}
+
+func (l keyLister) ListKeys(context.Context, int, ListKeysParams) (*v1.ServicePrincipalsServicePrincipalIDKeysGetOK, error) {
+	return &v1.ServicePrincipalsServicePrincipalIDKeysGetOK{Items: l.keys, Count: len(l.keys), Next: v1.NilURI{Null: true}}, nil
+}
+
+func TestExportJWKS_Skips(t *testing.T) {
+	assert := require.New(t)
+	_, public, err := GenerateKeyPair(KeyOptions{})
+	assert.NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(ecKey.Public())
+	assert.NoError(err)
+	ec := v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	enabled := v1.ServicePrincipalKeyStatusEnabled
+	api := keyLister{keys: []v1.ServicePrincipalKey{
+		{Kid: "good", Status: enabled, PublicKey: public, KeyExpiresAt: v1.NewOptNilString(time.Now().Add(time.Hour).Format(time.RFC3339))},
+		{Kid: "expired", Status: enabled, PublicKey: public, KeyExpiresAt: v1.NewOptNilString(time.Now().Add(-time.Hour).Format(time.RFC3339))},
+		{Kid: "ec", Status: enabled, PublicKey: ec},
+		{Kid: "garbage", Status: enabled, PublicKey: "garbage"},
+	}}
+
+	set, err := ExportJWKS(t.Context(), api, 1)
+	assert.ErrorContains(err, "key ec: unsupported key type")
+	assert.ErrorContains(err, "key garbage: no PEM block found")
+	assert.NotNil(set)
+	assert.Len(set.Keys, 1)
+	assert.Equal("good", set.Keys[0].Kid)
+}
@@ -0,0 +1,102 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// RSA key sizes UploadKey accepts.
+const (
+	MinRSABits = 2048
+	MaxRSABits = 4096
+)
+
+// KeyOptions describes the key pair to generate.  The API takes RSA keys
+// only, so that is all GenerateKeyPair makes.
+type KeyOptions struct {
+	Bits int // defaults to MinRSABits
+}
+
+// ProvisionedKey is a key ProvisionKey generated and uploaded.
+type ProvisionedKey struct {
+	ServicePrincipalID int
+	Key                *v1.ServicePrincipalKey
+	Signer             crypto.Signer
+
+	// The private key, PEM encoded in PKCS #8 form.  ParsePrivateKey reads
+	// it back, and so does saclient from SAKURA_PRIVATE_KEY.
+	PrivateKeyPEM []byte
+}
+
+// AssertionParams returns the parameters to sign assertions with k.
+func (k *ProvisionedKey) AssertionParams() AssertionParams {
+	return AssertionParams{ServicePrincipalID: k.ServicePrincipalID, Kid: k.Key.Kid, Key: k.Signer}
+}
+
+// GenerateKeyPair makes a new key pair and returns the private key with the
+// public one encoded for UploadKey.
+func GenerateKeyPair(opts KeyOptions) (crypto.Signer, v1.ServiceprincipalKeyPublicKey, error) {
+	bits := opts.Bits
+	if bits == 0 {
+		bits = MinRSABits
+	}
+	if bits < MinRSABits || bits > MaxRSABits {
+		return nil, "", common.NewError("ServicePrincipal.GenerateKeyPair", errors.Errorf("RSA keys must be %d to %d bits, not %d", MinRSABits, MaxRSABits, bits))
+	}
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, "", common.NewError("ServicePrincipal.GenerateKeyPair", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, "", common.NewError("ServicePrincipal.GenerateKeyPair", err)
+	}
+	return key, v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// ProvisionKey generates a key pair and uploads its public half to the
+// service principal id.  The private half only exists in the result.
+func ProvisionKey(ctx context.Context, api ServicePrincipalAPI, id int, opts KeyOptions) (*ProvisionedKey, error) {
+	signer, public, err := GenerateKeyPair(opts)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, common.NewError("ServicePrincipal.ProvisionKey", err)
+	}
+
+	k, err := api.UploadKey(ctx, id, public)
+	if err != nil {
+		return nil, err
+	}
+	return &ProvisionedKey{
+		ServicePrincipalID: id,
+		Key:                k,
+		Signer:             signer,
+		PrivateKeyPEM:      pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}),
+	}, nil
+}
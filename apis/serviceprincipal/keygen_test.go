@@ -0,0 +1,66 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal_test
+
+import (
+	"crypto/rsa"
+	"testing"
+
+	"github.com/sacloud/iam-api-go/apis/project"
+	. "github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvisionKey(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	api := NewServicePrincipalOp(client)
+
+	p, err := project.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	sp, err := api.Create(ctx, CreateParams{ProjectID: p.ID, Name: "sp"})
+	assert.NoError(err)
+
+	k, err := ProvisionKey(ctx, api, sp.ID, KeyOptions{})
+	assert.NoError(err)
+	assert.NotEmpty(k.Key.Kid)
+	assert.Equal(sp.ID, k.ServicePrincipalID)
+
+	parsed, err := ParsePrivateKey(k.PrivateKeyPEM)
+	assert.NoError(err)
+	assert.True(parsed.(*rsa.PrivateKey).Equal(k.Signer))
+
+	params := k.AssertionParams()
+	params.Key = parsed
+	token, err := IssueTokenWithKey(ctx, api, params)
+	assert.NoError(err)
+	assert.NotEmpty(token.AccessToken)
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	assert := require.New(t)
+
+	key, public, err := GenerateKeyPair(KeyOptions{Bits: 3072})
+	assert.NoError(err)
+	assert.Equal(3072, key.(*rsa.PrivateKey).N.BitLen())
+	assert.Contains(string(public), "-----BEGIN PUBLIC KEY-----")
+
+	_, _, err = GenerateKeyPair(KeyOptions{Bits: 1024})
+	assert.ErrorContains(err, "2048 to 4096 bits")
+	_, _, err = GenerateKeyPair(KeyOptions{Bits: 8192})
+	assert.ErrorContains(err, "2048 to 4096 bits")
+}
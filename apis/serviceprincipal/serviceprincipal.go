@@ -14,6 +14,7 @@ package serviceprincipal
 
 import (
 	"context"
+	"iter"
 
 	"github.com/google/uuid"
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
@@ -152,3 +153,31 @@ func (s *servicePrincipalOp) IssueToken(ctx context.Context, assertion string) (
 		})
 	})
 }
+
+// All iterates over every service principal matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api ServicePrincipalAPI, params ListParams) iter.Seq2[v1.ServicePrincipal, error] {
+	return common.Paginate[v1.ServicePrincipal](ctx, "ServicePrincipal.All", params.Page, func(page int) (*v1.ServicePrincipalsGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every service principal matching params.
+func ListAll(ctx context.Context, api ServicePrincipalAPI, params ListParams) ([]v1.ServicePrincipal, error) {
+	return common.Collect(All(ctx, api, params))
+}
+
+// AllKeys iterates over every key of the service principal, fetching pages on
+// demand.  params.Page, if set, is the page to start from.
+func AllKeys(ctx context.Context, api ServicePrincipalAPI, id int, params ListKeysParams) iter.Seq2[v1.ServicePrincipalKey, error] {
+	return common.Paginate[v1.ServicePrincipalKey](ctx, "ServicePrincipal.AllKeys", params.Page, func(page int) (*v1.ServicePrincipalsServicePrincipalIDKeysGetOK, error) {
+		params.Page = &page
+		return api.ListKeys(ctx, id, params)
+	})
+}
+
+// ListAllKeys collects every key of the service principal.
+func ListAllKeys(ctx context.Context, api ServicePrincipalAPI, id int, params ListKeysParams) ([]v1.ServicePrincipalKey, error) {
+	return common.Collect(AllKeys(ctx, api, id, params))
+}
@@ -68,6 +68,32 @@ func TestList_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestListAll(t *testing.T) {
+	var expected v1.ServicePrincipalsGetOK
+	expected.SetFake()
+	expected.SetItems(make([]v1.ServicePrincipal, 2))
+	expected.Items[0].SetFake()
+	expected.Items[1].SetFake()
+	expected.SetCount(2)
+	assert, api := setup(t, &expected)
+
+	actual, err := ListAll(t.Context(), api, ListParams{})
+	assert.NoError(err)
+	assert.Equal(expected.GetItems(), actual)
+}
+
+func TestListAll_Fail(t *testing.T) {
+	var res v1.Http403Forbidden
+	res.SetFake()
+	res.SetStatus(http.StatusForbidden)
+	assert, api := setup(t, &res, res.Status)
+
+	for actual, err := range All(t.Context(), api, ListParams{}) {
+		assert.Error(err)
+		assert.Zero(actual)
+	}
+}
+
 func TestCreate(t *testing.T) {
 	var expected v1.ServicePrincipal
 	expected.SetFake()
@@ -198,6 +224,32 @@ func TestListKeys_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestListAllKeys(t *testing.T) {
+	var expected v1.ServicePrincipalsServicePrincipalIDKeysGetOK
+	expected.SetFake()
+	expected.SetItems(make([]v1.ServicePrincipalKey, 2))
+	expected.Items[0].SetFake()
+	expected.Items[1].SetFake()
+	expected.SetCount(2)
+	assert, api := setup(t, &expected)
+
+	actual, err := ListAllKeys(t.Context(), api, 123, ListKeysParams{})
+	assert.NoError(err)
+	assert.Equal(expected.GetItems(), actual)
+}
+
+func TestListAllKeys_Fail(t *testing.T) {
+	var res v1.Http403Forbidden
+	res.SetFake()
+	res.SetStatus(http.StatusForbidden)
+	assert, api := setup(t, &res, res.Status)
+
+	for actual, err := range AllKeys(t.Context(), api, 123, ListKeysParams{}) {
+		assert.Error(err)
+		assert.Zero(actual)
+	}
+}
+
 func TestUploadKey(t *testing.T) {
 	var expected v1.ServicePrincipalKey
 	expected.SetFake()
@@ -0,0 +1,174 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+	"golang.org/x/oauth2"
+)
+
+// DefaultRefreshBefore is how long before it expires a token is replaced by
+// default.
+const DefaultRefreshBefore = time.Minute
+
+// DefaultRefreshTimeout is how long a request for a token may take by
+// default.
+const DefaultRefreshTimeout = 30 * time.Second
+
+type TokenSourceOptions struct {
+	// Replace the cached token this long before it expires.  Defaults to
+	// DefaultRefreshBefore.
+	RefreshBefore time.Duration
+
+	// Give up a request for a token after this long.  The request outlives
+	// the callers waiting for it, so it needs a deadline of its own.
+	// Defaults to DefaultRefreshTimeout.
+	RefreshTimeout time.Duration
+}
+
+// TokenSource issues access tokens for a service principal with
+// IssueTokenWithKey and caches them.  It is safe for concurrent use.
+//
+// Once the cached token enters its last RefreshBefore, callers keep getting
+// it while a single request for the next one runs in the background; they
+// only wait when no valid token is left.  Callers asking at the same time
+// share one request.
+type TokenSource struct {
+	api            ServicePrincipalAPI
+	params         AssertionParams
+	refreshBefore  time.Duration
+	refreshTimeout time.Duration
+
+	mu     sync.Mutex
+	token  *oauth2.Token
+	flight *flight
+}
+
+// a request for a token, shared by everyone waiting for it
+type flight struct {
+	done  chan struct{}
+	token *oauth2.Token
+	err   error
+}
+
+var _ oauth2.TokenSource = (*TokenSource)(nil)
+
+// NewTokenSource returns a TokenSource signing its assertions with params.
+// params.IssuedAt is ignored; every assertion is issued when it is built.
+func NewTokenSource(api ServicePrincipalAPI, params AssertionParams, opts TokenSourceOptions) *TokenSource {
+	params.IssuedAt = time.Time{}
+	if opts.RefreshBefore == 0 {
+		opts.RefreshBefore = DefaultRefreshBefore
+	}
+	if opts.RefreshTimeout == 0 {
+		opts.RefreshTimeout = DefaultRefreshTimeout
+	}
+	return &TokenSource{api: api, params: params, refreshBefore: opts.RefreshBefore, refreshTimeout: opts.RefreshTimeout}
+}
+
+// Token implements oauth2.TokenSource, so that ts can be handed to
+// oauth2.NewClient for instance.
+func (ts *TokenSource) Token() (*oauth2.Token, error) {
+	return ts.TokenContext(context.Background())
+}
+
+// TokenContext is Token, giving up waiting for a new token when ctx is done.
+// The request for it goes on for the benefit of other callers.
+func (ts *TokenSource) TokenContext(ctx context.Context) (*oauth2.Token, error) {
+	now := time.Now()
+
+	ts.mu.Lock()
+	token, f := ts.token, ts.flight
+	if token != nil && now.Before(token.Expiry.Add(-ts.refreshBefore)) {
+		ts.mu.Unlock()
+		return token, nil
+	}
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		ts.flight = f
+		go ts.refresh(context.WithoutCancel(ctx), f)
+	}
+	ts.mu.Unlock()
+
+	if token != nil && now.Before(token.Expiry) {
+		return token, nil
+	}
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (ts *TokenSource) refresh(ctx context.Context, f *flight) {
+	ctx, cancel := context.WithTimeout(ctx, ts.refreshTimeout)
+	defer cancel()
+	t, err := IssueTokenWithKey(ctx, ts.api, ts.params)
+	if err == nil {
+		f.token = convert(t)
+	} else {
+		f.err = err
+	}
+
+	ts.mu.Lock()
+	if err == nil {
+		ts.token = f.token
+	}
+	ts.flight = nil
+	ts.mu.Unlock()
+	close(f.done)
+}
+
+func convert(t *v1.ServicePrincipalOAuth2AccessToken) *oauth2.Token {
+	ret := &oauth2.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType.Or("Bearer"),
+		Expiry:      t.TokenExpiredAt,
+	}
+	if n, ok := t.ExpiresIn.Get(); ok {
+		ret.ExpiresIn = int64(n)
+		if ret.Expiry.IsZero() {
+			ret.Expiry = time.Now().Add(time.Duration(n) * time.Second)
+		}
+	}
+	return ret
+}
+
+// Middleware sets the Authorization header of every request to a token from
+// ts.  Pass it to saclient.WithMiddleware to make a saclient.Client
+// authenticate as the service principal.
+func (ts *TokenSource) Middleware() saclient.Middleware {
+	return func(req *http.Request, pull func() (saclient.Middleware, bool)) (*http.Response, error) {
+		token, err := ts.TokenContext(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		token.SetAuthHeader(req)
+
+		next, ok := pull()
+		if !ok {
+			return nil, saclient.NewErrorf("no next middleware to pull")
+		}
+		return next(req, pull)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceprincipal_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+// issues tokens valid for lifetime, numbered from 1, once release is closed
+// or gives up when the context is done
+type stubIssuer struct {
+	ServicePrincipalAPI
+	lifetime time.Duration
+	release  chan struct{}
+	calls    atomic.Int32
+	err      error
+}
+
+func (s *stubIssuer) IssueToken(ctx context.Context, assertion string) (*v1.ServicePrincipalOAuth2AccessToken, error) {
+	n := s.calls.Add(1)
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &v1.ServicePrincipalOAuth2AccessToken{
+		AccessToken:    strconv.Itoa(int(n)),
+		TokenExpiredAt: time.Now().Add(s.lifetime),
+	}, nil
+}
+
+func newTokenSource(t *testing.T, api *stubIssuer, opts TokenSourceOptions) *TokenSource {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return NewTokenSource(api, AssertionParams{ServicePrincipalID: 1, Kid: "kid", Key: key}, opts)
+}
+
+func TestTokenSource_Caches(t *testing.T) {
+	assert := require.New(t)
+	api := &stubIssuer{lifetime: time.Hour}
+	ts := newTokenSource(t, api, TokenSourceOptions{})
+
+	for range 3 {
+		token, err := ts.Token()
+		assert.NoError(err)
+		assert.Equal("1", token.AccessToken)
+		assert.Equal("Bearer", token.TokenType)
+	}
+	assert.EqualValues(1, api.calls.Load())
+}
+
+func TestTokenSource_RefreshesAhead(t *testing.T) {
+	assert := require.New(t)
+	api := &stubIssuer{lifetime: time.Hour}
+	ts := newTokenSource(t, api, TokenSourceOptions{RefreshBefore: 2 * time.Hour})
+
+	token, err := ts.Token()
+	assert.NoError(err)
+	assert.Equal("1", token.AccessToken)
+
+	// still valid: handed out while the next one is fetched
+	api.release = make(chan struct{})
+	token, err = ts.Token()
+	assert.NoError(err)
+	assert.Equal("1", token.AccessToken)
+	close(api.release)
+
+	assert.Eventually(func() bool {
+		token, err := ts.Token()
+		return err == nil && token.AccessToken != "1"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTokenSource_SharesRefresh(t *testing.T) {
+	assert := require.New(t)
+	api := &stubIssuer{lifetime: time.Hour, release: make(chan struct{})}
+	ts := newTokenSource(t, api, TokenSourceOptions{})
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	errs := make([]error, len(tokens))
+	for i := range tokens {
+		wg.Go(func() {
+			if token, err := ts.Token(); err != nil {
+				errs[i] = err
+			} else {
+				tokens[i] = token.AccessToken
+			}
+		})
+	}
+	assert.Eventually(func() bool { return api.calls.Load() == 1 }, time.Second, 10*time.Millisecond)
+	close(api.release)
+	wg.Wait()
+
+	assert.EqualValues(1, api.calls.Load())
+	assert.NoError(errors.Join(errs...))
+	for _, i := range tokens {
+		assert.Equal("1", i)
+	}
+}
+
+func TestTokenSource_Error(t *testing.T) {
+	assert := require.New(t)
+	api := &stubIssuer{err: errors.New("denied")}
+	ts := newTokenSource(t, api, TokenSourceOptions{})
+
+	_, err := ts.Token()
+	assert.ErrorContains(err, "denied")
+
+	// not cached
+	api.err = nil
+	api.lifetime = time.Hour
+	token, err := ts.Token()
+	assert.NoError(err)
+	assert.Equal("2", token.AccessToken)
+}
+
+func TestTokenSource_Context(t *testing.T) {
+	api := &stubIssuer{lifetime: time.Hour, release: make(chan struct{})}
+	defer close(api.release)
+	ts := newTokenSource(t, api, TokenSourceOptions{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := ts.TokenContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestTokenSource_RefreshTimeout(t *testing.T) {
+	assert := require.New(t)
+	api := &stubIssuer{lifetime: time.Hour, release: make(chan struct{})}
+	ts := newTokenSource(t, api, TokenSourceOptions{RefreshTimeout: 10 * time.Millisecond})
+
+	_, err := ts.Token()
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+	// the request that timed out is not waited for again
+	close(api.release)
+	token, err := ts.Token()
+	assert.NoError(err)
+	assert.Equal("2", token.AccessToken)
+}
+
+func TestTokenSource_Middleware(t *testing.T) {
+	assert := require.New(t)
+	ts := newTokenSource(t, &stubIssuer{lifetime: time.Hour}, TokenSourceOptions{})
+
+	var got string
+	last := func(req *http.Request, _ func() (saclient.Middleware, bool)) (*http.Response, error) {
+		got = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}
+	pull := func() (saclient.Middleware, bool) { return last, true }
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://example.com/", nil)
+	assert.NoError(err)
+	_, err = ts.Middleware()(req, pull)
+	assert.NoError(err)
+	assert.Equal("Bearer 1", got)
+	assert.Empty(req.Header.Get("Authorization"))
+}
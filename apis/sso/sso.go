@@ -14,6 +14,7 @@ package sso
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -85,3 +86,16 @@ func (s *ssoOp) Unlink(ctx context.Context, id int) (*v1.SSOProfile, error) {
 		return s.client.SSOProfilesSSOProfileIDUnassignPost(ctx, v1.SSOProfilesSSOProfileIDUnassignPostParams{SSOProfileID: id})
 	})
 }
+
+// All iterates over every SSO profile, fetching pages on demand.
+// page, if set, is the page to start from.
+func All(ctx context.Context, api SSOAPI, page, perPage *int) iter.Seq2[v1.SSOProfile, error] {
+	return common.Paginate[v1.SSOProfile](ctx, "SSO.All", page, func(page int) (*v1.SSOProfilesGetOK, error) {
+		return api.List(ctx, &page, perPage)
+	})
+}
+
+// ListAll collects every SSO profile.
+func ListAll(ctx context.Context, api SSOAPI, page, perPage *int) ([]v1.SSOProfile, error) {
+	return common.Collect(All(ctx, api, page, perPage))
+}
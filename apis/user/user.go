@@ -17,6 +17,7 @@ package user
 
 import (
 	"context"
+	"iter"
 
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/iam-api-go/common"
@@ -129,3 +130,17 @@ func (u *userOp) UnregisterEmail(ctx context.Context, userID int) error {
 	})
 	return err
 }
+
+// All iterates over every user matching params, fetching pages on demand.
+// params.Page, if set, is the page to start from.
+func All(ctx context.Context, api UserAPI, params ListParams) iter.Seq2[v1.User, error] {
+	return common.Paginate[v1.User](ctx, "User.All", params.Page, func(page int) (*v1.CompatUsersGetOK, error) {
+		params.Page = &page
+		return api.List(ctx, params)
+	})
+}
+
+// ListAll collects every user matching params.
+func ListAll(ctx context.Context, api UserAPI, params ListParams) ([]v1.User, error) {
+	return common.Collect(All(ctx, api, params))
+}
@@ -20,6 +20,7 @@ import (
 
 	. "github.com/sacloud/iam-api-go/apis/user"
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
 	iam_test "github.com/sacloud/iam-api-go/testutil"
 	"github.com/sacloud/packages-go/testutil"
 	"github.com/sacloud/saclient-go"
@@ -69,6 +70,32 @@ func TestList_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestListAll(t *testing.T) {
+	var expected v1.CompatUsersGetOK
+	expected.SetFake()
+	expected.SetItems(make([]v1.User, 2))
+	expected.Items[0].SetFake()
+	expected.Items[1].SetFake()
+	expected.SetCount(2)
+	assert, api := setup(t, &expected)
+
+	actual, err := ListAll(t.Context(), api, ListParams{})
+	assert.NoError(err)
+	assert.Equal(expected.GetItems(), actual)
+}
+
+func TestListAll_Fail(t *testing.T) {
+	var res v1.Http403Forbidden
+	res.SetFake()
+	res.SetStatus(http.StatusForbidden)
+	assert, api := setup(t, &res, res.Status)
+
+	for actual, err := range All(t.Context(), api, ListParams{}) {
+		assert.Error(err)
+		assert.Zero(actual)
+	}
+}
+
 func TestCreate(t *testing.T) {
 	var expected v1.User
 	expected.SetFake()
@@ -104,6 +131,27 @@ func TestCreate_Fail(t *testing.T) {
 	assert.Contains(err.Error(), expected)
 }
 
+func TestCreate_ValidationErrors(t *testing.T) {
+	var res v1.Http400BadRequest
+	res.SetFake()
+	res.SetStatus(http.StatusBadRequest)
+	res.Errors.SetAdditionalProps(v1.Http400BadRequestErrorsAdditional{
+		"code": {{Message: "already taken", Code: "unique"}},
+	})
+	assert, api := setup(t, &res, res.Status)
+
+	params := CreateParams{
+		Name:     testutil.RandomName("user", 32, testutil.CharSetAlphaNum),
+		Password: testutil.Random(16, testutil.CharSetAlphaNum),
+	}
+	_, err := api.Create(t.Context(), params)
+	assert.Error(err)
+
+	var actual common.ValidationErrors
+	assert.ErrorAs(err, &actual)
+	assert.Equal([]string{"already taken"}, actual["code"])
+}
+
 func TestGet(t *testing.T) {
 	var expected v1.User
 	expected.SetFake()
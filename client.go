@@ -51,7 +51,7 @@ func (voidSecuritySource) ServicePrincipalAuth(context.Context, v1.OperationName
 	return v1.ServicePrincipalAuth{}, nil
 }
 
-func NewClient(client saclient.ClientAPI) (*v1.Client, error) {
+func NewClient(client saclient.ClientAPI, opts ...Option) (*v1.Client, error) {
 	endpointConfig, err := client.EndpointConfig()
 	if err != nil {
 		return nil, NewError("unable to load endpoint configuration", err)
@@ -61,18 +61,37 @@ func NewClient(client saclient.ClientAPI) (*v1.Client, error) {
 		endpoint = ep
 	}
 
-	return NewClientWithAPIRootURL(client, endpoint)
+	return NewClientWithAPIRootURL(client, endpoint, opts...)
 }
 
-func NewClientWithAPIRootURL(client saclient.ClientAPI, apiRootURL string) (*v1.Client, error) {
-	if dupable, ok := client.(saclient.ClientOptionAPI); !ok {
+func NewClientWithAPIRootURL(client saclient.ClientAPI, apiRootURL string, opts ...Option) (*v1.Client, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	dupable, ok := client.(saclient.ClientOptionAPI)
+	if !ok {
 		return nil, NewError("client does not implement saclient.ClientOptionAPI", nil)
+	}
+
+	if o.retry == nil {
+		if augmented, err := dupable.DupWith(
+			saclient.WithUserAgent(UserAgent),
+			saclient.WithForceAutomaticAuthentication(),
+		); err != nil {
+			return nil, err
+		} else {
+			return v1.NewClient(apiRootURL, voidSecuritySource{}, v1.WithClient(augmented))
+		}
 	} else if augmented, err := dupable.DupWith(
 		saclient.WithUserAgent(UserAgent),
 		saclient.WithForceAutomaticAuthentication(),
+		saclient.WithoutRetry(),
 	); err != nil {
 		return nil, err
 	} else {
-		return v1.NewClient(apiRootURL, voidSecuritySource{}, v1.WithClient(augmented))
+		doer := &retryDoer{next: augmented, policy: *o.retry}
+		return v1.NewClient(apiRootURL, voidSecuritySource{}, v1.WithClient(doer))
 	}
 }
@@ -22,6 +22,7 @@ import (
 
 	. "github.com/sacloud/iam-api-go"
 	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/saclient-go"
 	"github.com/stretchr/testify/require"
 )
@@ -56,6 +57,47 @@ func TestNewClient_WithCustomEndpoint(t *testing.T) {
 	assert.Len(requests, 1)
 }
 
+func TestNew(t *testing.T) {
+	assert := require.New(t)
+
+	tracker := newMockRequestTracker()
+	defer tracker.Close()
+
+	var theClient saclient.Client
+	client, err := NewWithAPIRootURL(&theClient, tracker.URL())
+	assert.NoError(err)
+	assert.NotNil(client.V1())
+
+	_, _ = client.Users().List(t.Context(), user.ListParams{})
+	assert.Len(tracker.Requests(), 1)
+}
+
+func TestClient_Lazy(t *testing.T) {
+	assert := require.New(t)
+
+	var theClient saclient.Client
+	client, err := New(&theClient)
+	assert.NoError(err)
+
+	var api ClientAPI = client
+	assert.Same(api.Users(), api.Users())
+	assert.Same(api.Groups(), api.Groups())
+	assert.Same(api.IAMPolicy(), api.IAMPolicy())
+	assert.NotNil(api.Auth())
+	assert.NotNil(api.Folders())
+	assert.NotNil(api.IAMRoles())
+	assert.NotNil(api.IDPolicy())
+	assert.NotNil(api.IDRoles())
+	assert.NotNil(api.Organization())
+	assert.NotNil(api.Projects())
+	assert.NotNil(api.ProjectAPIKeys())
+	assert.NotNil(api.Scim())
+	assert.NotNil(api.ServicePolicy())
+	assert.NotNil(api.ServicePrincipals())
+	assert.NotNil(api.SSO())
+	assert.NotNil(api.User2FA(&v1.User{ID: 1}))
+}
+
 type mockRequestTracker struct {
 	mu       sync.Mutex
 	requests []*http.Request
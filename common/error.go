@@ -16,6 +16,8 @@ package common
 
 import (
 	"encoding/json"
+	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/go-faster/errors"
@@ -25,8 +27,9 @@ import (
 )
 
 type Error struct {
-	msg string
-	err error
+	msg  string
+	err  error
+	code int
 }
 
 func (e *Error) Unwrap() error { return e.err }
@@ -50,11 +53,75 @@ func (e *Error) Error() string {
 
 func NewError(msg string, err error) *Error { return &Error{msg: msg, err: err} }
 func NewAPIError(method string, code int, err error) *Error {
-	return NewError(method, saclient.NewError(code, "", err))
+	e := NewError(method, saclient.NewError(code, "", err))
+	e.code = code
+	return e
+}
+
+// Problem is the RFC 7807 style body that the API returns on errors.
+// Errors is only populated for 400 Bad Request.
+type Problem struct {
+	Type   string
+	Status int
+	Title  string
+	Detail string
+	Errors *v1.Http400BadRequestErrors
+}
+
+// NonFieldErrors is the ValidationErrors key for errors that do not relate
+// to any specific field of the request body.
+const NonFieldErrors = "non_field_errors"
+
+// ValidationErrors maps a request body field to the messages the API
+// reported against it.  Errors not tied to a field are keyed NonFieldErrors.
+type ValidationErrors map[string][]string
+
+// NewValidationErrors extracts ValidationErrors from a 400 response body.
+func NewValidationErrors(e *v1.Http400BadRequestErrors) ValidationErrors {
+	if e == nil {
+		return nil
+	}
+
+	ret := make(ValidationErrors)
+	for _, i := range e.GetNonFieldErrors() {
+		ret[NonFieldErrors] = append(ret[NonFieldErrors], i.GetMessage())
+	}
+	for k, v := range e.GetAdditionalProps() {
+		for _, i := range v {
+			ret[k] = append(ret[k], i.GetMessage())
+		}
+	}
+	return ret
+}
+
+// Fields returns the keys of v in sorted order.
+func (v ValidationErrors) Fields() []string {
+	ret := make([]string, 0, len(v))
+	for k := range v {
+		ret = append(ret, k)
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+func (v ValidationErrors) Error() string {
+	var buf strings.Builder
+
+	for i, k := range v.Fields() {
+		if i > 0 {
+			buf.WriteString("; ")
+		}
+		buf.WriteString(k)
+		buf.WriteString(": ")
+		buf.WriteString(strings.Join(v[k], ", "))
+	}
+
+	return buf.String()
 }
 
 type apiErrorResponse struct {
-	j json.Marshaler
+	j       json.Marshaler
+	problem Problem
 }
 
 func (e *apiErrorResponse) Error() string {
@@ -66,11 +133,23 @@ func (e *apiErrorResponse) Error() string {
 	}
 }
 
+// Unwrap exposes the field-level details of a 400 response as
+// ValidationErrors, so that errors.As can reach them.
+func (e *apiErrorResponse) Unwrap() error {
+	if v := NewValidationErrors(e.problem.Errors); len(v) > 0 {
+		return v
+	} else {
+		return nil
+	}
+}
+
 func newAPIErrorFromResponse[
 	T any,
 	E interface {
 		json.Marshaler
+		GetType() string
 		GetStatus() int
+		GetTitle() string
 		GetDetail() string
 	},
 ](
@@ -80,17 +159,29 @@ func newAPIErrorFromResponse[
 	t *T,
 	e *Error,
 ) {
+	r := &apiErrorResponse{
+		j: error,
+		problem: Problem{
+			Type:   error.GetType(),
+			Status: error.GetStatus(),
+			Title:  error.GetTitle(),
+			Detail: error.GetDetail(),
+		},
+	}
+	if b, ok := any(error).(*v1.Http400BadRequest); ok {
+		r.problem.Errors = &b.Errors
+	}
+
 	t = (*T)(nil)
 	e = NewError(
 		method,
 		saclient.NewError(
 			error.GetStatus(),
 			error.GetDetail(),
-			&apiErrorResponse{
-				j: error,
-			},
+			r,
 		),
 	)
+	e.code = error.GetStatus()
 	return
 }
 
@@ -124,3 +215,54 @@ func ErrorFromDecodedResponse[T any](method string, yield func() (any, error)) (
 		}
 	}
 }
+
+// StatusCode returns the HTTP status code the API responded with, or 0 if err
+// did not originate from an API response.
+func StatusCode(err error) int {
+	for e := err; e != nil; {
+		if i, ok := errors.Into[*Error](e); !ok {
+			break
+		} else if i.code != 0 {
+			return i.code
+		} else {
+			e = i.err
+		}
+	}
+	if e, ok := errors.Into[*ogen.UnexpectedStatusCodeError](err); ok {
+		return e.StatusCode
+	}
+	return 0
+}
+
+// AsProblem returns the decoded error body carried by err, if any.
+func AsProblem(err error) (*Problem, bool) {
+	if r, ok := errors.Into[*apiErrorResponse](err); ok {
+		p := r.problem
+		return &p, true
+	} else {
+		return nil, false
+	}
+}
+
+// IsValidation reports whether err is a 400 Bad Request.
+func IsValidation(err error) bool { return StatusCode(err) == http.StatusBadRequest }
+
+// IsUnauthorized reports whether err is a 401 Unauthorized.
+func IsUnauthorized(err error) bool { return StatusCode(err) == http.StatusUnauthorized }
+
+// IsForbidden reports whether err is a 403 Forbidden.
+func IsForbidden(err error) bool { return StatusCode(err) == http.StatusForbidden }
+
+// IsNotFound reports whether err is a 404 Not Found.
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound || saclient.IsNotFoundError(err)
+}
+
+// IsConflict reports whether err is a 409 Conflict.
+func IsConflict(err error) bool { return StatusCode(err) == http.StatusConflict }
+
+// IsRateLimited reports whether err is a 429 Too Many Requests.
+func IsRateLimited(err error) bool { return StatusCode(err) == http.StatusTooManyRequests }
+
+// IsUnavailable reports whether err is a 503 Service Unavailable.
+func IsUnavailable(err error) bool { return StatusCode(err) == http.StatusServiceUnavailable }
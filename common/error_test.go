@@ -16,8 +16,11 @@ package common
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
 	"github.com/sacloud/saclient-go"
 	"github.com/stretchr/testify/require"
 )
@@ -79,3 +82,138 @@ func TestNewAPIError(t *testing.T) {
 	assert.Equal("msg", err2.msg)
 	assert.False(saclient.IsNotFoundError(err2))
 }
+
+func TestErrorFromDecodedResponse_Classification(t *testing.T) {
+	var bad v1.Http400BadRequest
+	bad.SetFake()
+	bad.SetStatus(http.StatusBadRequest)
+	bad.SetTitle("bad request")
+	bad.SetDetail("detail")
+	bad.Errors.SetNonFieldErrors([]v1.Http400BadRequestErrorsNonFieldErrorsItem{{Message: "m", Code: "c"}})
+
+	var forbidden v1.Http403Forbidden
+	forbidden.SetFake()
+	forbidden.SetStatus(http.StatusForbidden)
+
+	var notFound v1.Http404NotFound
+	notFound.SetFake()
+	notFound.SetStatus(http.StatusNotFound)
+
+	var conflict v1.Http409Conflict
+	conflict.SetFake()
+	conflict.SetStatus(http.StatusConflict)
+
+	var tooMany v1.Http429TooManyRequests
+	tooMany.SetFake()
+	tooMany.SetStatus(http.StatusTooManyRequests)
+
+	var unavailable v1.Http503ServiceUnavailable
+	unavailable.SetFake()
+	unavailable.SetStatus(http.StatusServiceUnavailable)
+
+	tests := []struct {
+		name string
+		resp any
+		code int
+		pred func(error) bool
+	}{
+		{"400", &bad, http.StatusBadRequest, IsValidation},
+		{"403", &forbidden, http.StatusForbidden, IsForbidden},
+		{"404", &notFound, http.StatusNotFound, IsNotFound},
+		{"409", &conflict, http.StatusConflict, IsConflict},
+		{"429", &tooMany, http.StatusTooManyRequests, IsRateLimited},
+		{"503", &unavailable, http.StatusServiceUnavailable, IsUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := require.New(t)
+			_, err := ErrorFromDecodedResponse[v1.Group]("Test", func() (any, error) { return tt.resp, nil })
+			assert.Error(err)
+			assert.Equal(tt.code, StatusCode(err))
+			assert.True(tt.pred(err))
+			assert.False(IsUnauthorized(err))
+
+			p, ok := AsProblem(fmt.Errorf("wrapped: %w", err))
+			assert.True(ok)
+			assert.Equal(tt.code, p.Status)
+		})
+	}
+}
+
+func TestAsProblem(t *testing.T) {
+	assert := require.New(t)
+
+	var bad v1.Http400BadRequest
+	bad.SetFake()
+	bad.SetType("about:blank")
+	bad.SetStatus(http.StatusBadRequest)
+	bad.SetTitle("bad request")
+	bad.SetDetail("detail")
+	_, err := ErrorFromDecodedResponse[v1.Group]("Test", func() (any, error) { return &bad, nil })
+
+	p, ok := AsProblem(err)
+	assert.True(ok)
+	assert.Equal("about:blank", p.Type)
+	assert.Equal("bad request", p.Title)
+	assert.Equal("detail", p.Detail)
+	assert.Equal(&bad.Errors, p.Errors)
+
+	_, ok = AsProblem(NewError("msg", errors.New("base error")))
+	assert.False(ok)
+}
+
+func TestStatusCode(t *testing.T) {
+	assert := require.New(t)
+
+	assert.Equal(http.StatusServiceUnavailable, StatusCode(NewAPIError("msg", 503, nil)))
+	assert.True(IsUnavailable(NewAPIError("msg", 503, nil)))
+	assert.True(IsNotFound(NewAPIError("msg", 404, nil)))
+	assert.Equal(0, StatusCode(NewError("msg", errors.New("base error"))))
+	assert.Equal(0, StatusCode(nil))
+	assert.False(IsNotFound(nil))
+}
+
+func TestValidationErrors(t *testing.T) {
+	assert := require.New(t)
+
+	var bad v1.Http400BadRequest
+	bad.SetFake()
+	bad.SetStatus(http.StatusBadRequest)
+	bad.Errors.SetNonFieldErrors([]v1.Http400BadRequestErrorsNonFieldErrorsItem{
+		{Message: "invalid request", Code: "invalid"},
+	})
+	bad.Errors.SetAdditionalProps(v1.Http400BadRequestErrorsAdditional{
+		"name": {
+			{Message: "too long", Code: "max_length"},
+			{Message: "invalid character", Code: "invalid"},
+		},
+		"code": {
+			{Message: "required", Code: "required"},
+		},
+	})
+	_, err := ErrorFromDecodedResponse[v1.Group]("Test", func() (any, error) { return &bad, nil })
+
+	var actual ValidationErrors
+	assert.ErrorAs(fmt.Errorf("wrapped: %w", err), &actual)
+	assert.Equal(ValidationErrors{
+		NonFieldErrors: {"invalid request"},
+		"name":         {"too long", "invalid character"},
+		"code":         {"required"},
+	}, actual)
+	assert.Equal([]string{"code", "name", NonFieldErrors}, actual.Fields())
+	assert.Equal("code: required; name: too long, invalid character; non_field_errors: invalid request", actual.Error())
+}
+
+func TestValidationErrors_NotBadRequest(t *testing.T) {
+	assert := require.New(t)
+
+	var res v1.Http404NotFound
+	res.SetFake()
+	res.SetStatus(http.StatusNotFound)
+	_, err := ErrorFromDecodedResponse[v1.Group]("Test", func() (any, error) { return &res, nil })
+
+	var actual ValidationErrors
+	assert.False(errors.As(err, &actual))
+	assert.Nil(NewValidationErrors(nil))
+}
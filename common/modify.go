@@ -0,0 +1,99 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-faster/errors"
+)
+
+// ErrConcurrentModification is reported by Modify when other writers kept
+// changing a document for MaxModifyAttempts rounds in a row.
+var ErrConcurrentModification = errors.New("document was modified concurrently")
+
+// MaxModifyAttempts bounds how many times Modify starts over.
+const MaxModifyAttempts = 5
+
+// waited between attempts, multiplied by the attempt number
+var modifyBackoff = 100 * time.Millisecond
+
+// Modify is an optimistic read-modify-write of a document that the API only
+// lets us replace as a whole, such as an IAM policy.
+//
+// fn receives the current document and returns the desired one.  Right
+// before writing, the document is read once more; if somebody else changed
+// it in the meantime (or the write is answered with 409 Conflict) the whole
+// cycle starts over with the fresh copy.  If fn changes nothing, nothing is
+// written.
+//
+// The API offers no version tag, so a write racing with another between the
+// second read and the write itself can still go unnoticed.  Modify narrows
+// that window; it cannot close it.
+func Modify[T any](
+	ctx context.Context,
+	method string,
+	read func() (T, error),
+	write func(T) (T, error),
+	fn func(T) T,
+) (T, error) {
+	var zero T
+
+	for n := 1; ; n++ {
+		current, err := read()
+		if err != nil {
+			return zero, err
+		}
+		before, err := json.Marshal(current)
+		if err != nil {
+			return zero, NewError(method, err)
+		}
+
+		desired := fn(current)
+		if after, err := json.Marshal(desired); err != nil {
+			return zero, NewError(method, err)
+		} else if bytes.Equal(before, after) {
+			return desired, nil
+		}
+
+		again, err := read()
+		if err != nil {
+			return zero, err
+		}
+		if now, err := json.Marshal(again); err != nil {
+			return zero, NewError(method, err)
+		} else if bytes.Equal(before, now) {
+			ret, err := write(desired)
+			if !IsConflict(err) {
+				return ret, err
+			}
+		}
+
+		if n >= MaxModifyAttempts {
+			return zero, NewError(method, ErrConcurrentModification)
+		}
+
+		t := time.NewTimer(time.Duration(n) * modifyBackoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return zero, NewError(method, ctx.Err())
+		case <-t.C:
+		}
+	}
+}
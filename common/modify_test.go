@@ -0,0 +1,137 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// document is a store whose content other writers may change at each read.
+type document struct {
+	value  []string
+	reads  int
+	writes int
+	meddle func(reads int) []string
+	status int
+}
+
+func (d *document) read() ([]string, error) {
+	d.reads++
+	if d.meddle != nil {
+		if v := d.meddle(d.reads); v != nil {
+			d.value = v
+		}
+	}
+	return append([]string{}, d.value...), nil
+}
+
+func (d *document) write(v []string) ([]string, error) {
+	d.writes++
+	if d.status != 0 {
+		return nil, NewAPIError("Test", d.status, errors.New("rejected"))
+	}
+	d.value = v
+	return v, nil
+}
+
+func add(s string) func([]string) []string {
+	return func(v []string) []string { return append(v, s) }
+}
+
+func init() { modifyBackoff = time.Millisecond }
+
+func TestModify(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}}
+
+	actual, err := Modify(t.Context(), "Test", d.read, d.write, add("b"))
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b"}, actual)
+	assert.Equal([]string{"a", "b"}, d.value)
+	assert.Equal(1, d.writes)
+}
+
+func TestModify_Unchanged(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}}
+
+	actual, err := Modify(t.Context(), "Test", d.read, d.write, func(v []string) []string { return v })
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, actual)
+	assert.Zero(d.writes)
+}
+
+func TestModify_Retry(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}}
+	d.meddle = func(n int) []string {
+		if n == 2 {
+			return []string{"a", "x"}
+		}
+		return nil
+	}
+
+	actual, err := Modify(t.Context(), "Test", d.read, d.write, add("b"))
+	assert.NoError(err)
+	assert.Equal([]string{"a", "x", "b"}, actual)
+	assert.Equal(1, d.writes)
+	assert.Equal(4, d.reads)
+}
+
+func TestModify_Conflict(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}, status: http.StatusConflict}
+
+	_, err := Modify(t.Context(), "Test", d.read, d.write, add("b"))
+	assert.ErrorIs(err, ErrConcurrentModification)
+	assert.Equal(MaxModifyAttempts, d.writes)
+}
+
+func TestModify_GiveUp(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{}}
+	d.meddle = func(n int) []string { return []string{string(rune('a' + n))} }
+
+	_, err := Modify(t.Context(), "Test", d.read, d.write, add("b"))
+	assert.ErrorIs(err, ErrConcurrentModification)
+	assert.Zero(d.writes)
+	assert.Equal(2*MaxModifyAttempts, d.reads)
+}
+
+func TestModify_OtherError(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}, status: http.StatusBadRequest}
+
+	_, err := Modify(t.Context(), "Test", d.read, d.write, add("b"))
+	assert.True(IsValidation(err))
+	assert.Equal(1, d.writes)
+}
+
+func TestModify_Canceled(t *testing.T) {
+	assert := require.New(t)
+	d := document{value: []string{"a"}, status: http.StatusConflict}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := Modify(ctx, "Test", d.read, d.write, add("b"))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(1, d.writes)
+}
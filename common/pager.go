@@ -0,0 +1,96 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"iter"
+	"net/url"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// Page is what every paginated `*GetOK` response looks like.
+type Page[T any] interface {
+	GetItems() []T
+	GetCount() int
+	GetNext() v1.NilURI
+}
+
+// Paginate turns a page-numbered List call into an iterator.  Pages are
+// fetched lazily, starting from `page` (or the first one if nil), until the
+// server stops advertising a next page.  Context cancellation is checked
+// before every page and every item; it is yielded as an error and ends the
+// iteration.
+func Paginate[T any, P Page[T]](
+	ctx context.Context,
+	method string,
+	page *int,
+	fetch func(page int) (P, error),
+) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		var seen int
+
+		n := 1
+		if page != nil {
+			n = *page
+		}
+
+		for ; ; n++ {
+			if err := ctx.Err(); err != nil {
+				yield(zero, NewError(method, err))
+				return
+			}
+
+			p, err := fetch(n)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			items := p.GetItems()
+			for _, i := range items {
+				if err := ctx.Err(); err != nil {
+					yield(zero, NewError(method, err))
+					return
+				}
+				if !yield(i, nil) {
+					return
+				}
+			}
+
+			seen += len(items)
+			if next := p.GetNext(); next.Null || next.Value == (url.URL{}) {
+				return
+			} else if len(items) == 0 || seen >= p.GetCount() {
+				return
+			}
+		}
+	}
+}
+
+// Collect drains an iterator built by Paginate.  On error, items read so far
+// are discarded.
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var ret []T
+	for t, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// pages returns a fetcher serving `total` groups split into pages of `per`.
+func pages(total, per int, calls *[]int) func(int) (*v1.GroupsGetOK, error) {
+	return func(page int) (*v1.GroupsGetOK, error) {
+		*calls = append(*calls, page)
+		var ret v1.GroupsGetOK
+		ret.SetCount(total)
+		for i := (page - 1) * per; i < min(page*per, total); i++ {
+			ret.Items = append(ret.Items, v1.Group{ID: i})
+		}
+		if page*per < total {
+			ret.SetNext(v1.NilURI{Value: url.URL{Scheme: "https", Host: "example.com"}})
+		} else {
+			ret.SetNext(v1.NilURI{Null: true})
+		}
+		return &ret, nil
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	assert := require.New(t)
+	var calls []int
+
+	actual, err := Collect(Paginate[v1.Group](t.Context(), "Test", nil, pages(7, 3, &calls)))
+	assert.NoError(err)
+	assert.Len(actual, 7)
+	assert.Equal([]int{1, 2, 3}, calls)
+	for i, g := range actual {
+		assert.Equal(i, g.ID)
+	}
+}
+
+func TestPaginate_StartPage(t *testing.T) {
+	assert := require.New(t)
+	var calls []int
+	page := 2
+
+	actual, err := Collect(Paginate[v1.Group](t.Context(), "Test", &page, pages(7, 3, &calls)))
+	assert.NoError(err)
+	assert.Len(actual, 4)
+	assert.Equal([]int{2, 3}, calls)
+}
+
+func TestPaginate_Break(t *testing.T) {
+	assert := require.New(t)
+	var calls []int
+
+	for g, err := range Paginate[v1.Group](t.Context(), "Test", nil, pages(7, 3, &calls)) {
+		assert.NoError(err)
+		if g.ID == 1 {
+			break
+		}
+	}
+	assert.Equal([]int{1}, calls)
+}
+
+func TestPaginate_Cancel(t *testing.T) {
+	assert := require.New(t)
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var calls []int
+	var seen int
+
+	for _, err := range Paginate[v1.Group](ctx, "Test", nil, pages(7, 3, &calls)) {
+		if err != nil {
+			assert.ErrorIs(err, context.Canceled)
+			break
+		}
+		if seen++; seen == 2 {
+			cancel()
+		}
+	}
+	assert.Equal(2, seen)
+	assert.Equal([]int{1}, calls)
+}
+
+func TestPaginate_Fail(t *testing.T) {
+	assert := require.New(t)
+	expected := errors.New("failure")
+	fetch := func(int) (*v1.GroupsGetOK, error) { return nil, expected }
+
+	actual, err := Collect(Paginate[v1.Group](t.Context(), "Test", nil, fetch))
+	assert.ErrorIs(err, expected)
+	assert.Nil(actual)
+}
@@ -0,0 +1,204 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"slices"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// PrincipalType is what v1.Principal.Type holds.  The schema leaves it a
+// free-form string; these are the values the API accepts.
+type PrincipalType string
+
+const (
+	PrincipalTypeUser             PrincipalType = "user"
+	PrincipalTypeGroup            PrincipalType = "group"
+	PrincipalTypeServicePrincipal PrincipalType = "service-principal"
+)
+
+func (t PrincipalType) AllValues() []PrincipalType {
+	return []PrincipalType{
+		PrincipalTypeUser,
+		PrincipalTypeGroup,
+		PrincipalTypeServicePrincipal,
+	}
+}
+
+// Principal returns the principal of this type with the given resource ID.
+func (t PrincipalType) Principal(id int) v1.Principal {
+	return v1.Principal{Type: v1.NewOptString(string(t)), ID: v1.NewOptInt(id)}
+}
+
+// PrincipalTypeOf returns the type of p, or "" if it has none.
+func PrincipalTypeOf(p v1.Principal) PrincipalType { return PrincipalType(p.Type.Value) }
+
+func UserPrincipal(id int) v1.Principal  { return PrincipalTypeUser.Principal(id) }
+func GroupPrincipal(id int) v1.Principal { return PrincipalTypeGroup.Principal(id) }
+func ServicePrincipalPrincipal(id int) v1.Principal {
+	return PrincipalTypeServicePrincipal.Principal(id)
+}
+
+// PresetIAMRole refers to a predefined IAM role such as "owner".
+func PresetIAMRole(id string) v1.IamPolicyRole {
+	return v1.IamPolicyRole{
+		Type: v1.NewOptIamPolicyRoleType(v1.IamPolicyRoleTypePreset),
+		ID:   v1.NewOptString(id),
+	}
+}
+
+// PresetIDRole refers to a predefined ID role such as "identity-admin".
+func PresetIDRole(id string) v1.IdPolicyRole {
+	return v1.IdPolicyRole{
+		Type: v1.NewOptIdPolicyRoleType(v1.IdPolicyRoleTypePreset),
+		ID:   v1.NewOptString(id),
+	}
+}
+
+// IAMBinding grants a preset IAM role to principals.
+func IAMBinding(role string, principals ...v1.Principal) v1.IamPolicy {
+	return v1.IamPolicy{Role: v1.NewOptIamPolicyRole(PresetIAMRole(role)), Principals: principals}
+}
+
+// IDBinding grants a preset ID role to principals.
+func IDBinding(role string, principals ...v1.Principal) v1.IdPolicy {
+	return v1.IdPolicy{Role: v1.NewOptIdPolicyRole(PresetIDRole(role)), Principals: principals}
+}
+
+// Binding is an entry of an IAM policy or of the ID policy.
+type Binding interface {
+	v1.IamPolicy | v1.IdPolicy
+}
+
+// RoleOf returns the ID of the role b grants.
+func RoleOf[T Binding](b T) string {
+	switch b := any(b).(type) {
+	case v1.IamPolicy:
+		return b.Role.Value.ID.Value
+	case v1.IdPolicy:
+		return b.Role.Value.ID.Value
+	}
+	return ""
+}
+
+// SamePrincipal matches the principals of the same type and ID as p.
+func SamePrincipal(p v1.Principal) func(v1.Principal) bool {
+	return func(q v1.Principal) bool { return p.Type == q.Type && p.ID == q.ID }
+}
+
+// MergePrincipals appends those of add not yet in dst.  Merging into nil
+// removes duplicates from add.
+func MergePrincipals(dst, add []v1.Principal) []v1.Principal {
+	for _, p := range add {
+		if !slices.ContainsFunc(dst, SamePrincipal(p)) {
+			dst = append(dst, p)
+		}
+	}
+	return dst
+}
+
+// CloneBindings copies b deeply enough for the copy to be edited freely.
+func CloneBindings[T Binding](b []T) []T {
+	ret := make([]T, len(b))
+	for i, j := range b {
+		ret[i] = j
+		setPrincipals(&ret[i], slices.Clone(principalsOf(j)))
+	}
+	return ret
+}
+
+// GrantRole adds principals to the binding of role in b, creating it if
+// need be.  All the bindings of role are folded into the first one, each
+// principal appearing once, and bindings left without principals are
+// dropped.
+func GrantRole[T Binding](b []T, role string, principals ...v1.Principal) []T {
+	ret := make([]T, 0, len(b)+1)
+	var merged []v1.Principal
+	at := -1
+	for _, i := range b {
+		if RoleOf(i) != role {
+			ret = append(ret, i)
+			continue
+		}
+		merged = MergePrincipals(merged, principalsOf(i))
+		if at < 0 {
+			at = len(ret)
+			ret = append(ret, i)
+		}
+	}
+	merged = MergePrincipals(merged, principals)
+	if at < 0 {
+		ret = append(ret, binding[T](role, merged))
+	} else {
+		setPrincipals(&ret[at], merged)
+	}
+	return pruneBindings(ret)
+}
+
+// RevokeRole removes principals from every binding of role in b, dropping
+// the bindings left without principals.
+func RevokeRole[T Binding](b []T, role string, principals ...v1.Principal) []T {
+	for i := range b {
+		if RoleOf(b[i]) == role {
+			setPrincipals(&b[i], slices.DeleteFunc(principalsOf(b[i]), func(p v1.Principal) bool {
+				return slices.ContainsFunc(principals, SamePrincipal(p))
+			}))
+		}
+	}
+	return pruneBindings(b)
+}
+
+// SetRole makes principals the only holders of role in b.  No principals at
+// all removes the binding.
+func SetRole[T Binding](b []T, role string, principals ...v1.Principal) []T {
+	b = slices.DeleteFunc(b, func(i T) bool { return RoleOf(i) == role })
+	b = append(b, binding[T](role, MergePrincipals(nil, principals)))
+	return pruneBindings(b)
+}
+
+func pruneBindings[T Binding](b []T) []T {
+	return slices.DeleteFunc(b, func(i T) bool { return len(principalsOf(i)) == 0 })
+}
+
+func binding[T Binding](role string, principals []v1.Principal) T {
+	var ret T
+	switch b := any(&ret).(type) {
+	case *v1.IamPolicy:
+		*b = IAMBinding(role, principals...)
+	case *v1.IdPolicy:
+		*b = IDBinding(role, principals...)
+	}
+	return ret
+}
+
+func principalsOf[T Binding](b T) []v1.Principal {
+	switch b := any(b).(type) {
+	case v1.IamPolicy:
+		return b.Principals
+	case v1.IdPolicy:
+		return b.Principals
+	}
+	return nil
+}
+
+func setPrincipals[T Binding](b *T, principals []v1.Principal) {
+	switch b := any(b).(type) {
+	case *v1.IamPolicy:
+		b.Principals = principals
+	case *v1.IdPolicy:
+		b.Principals = principals
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrincipal(t *testing.T) {
+	assert := require.New(t)
+
+	j, err := json.Marshal(ServicePrincipalPrincipal(123))
+	assert.NoError(err)
+	assert.JSONEq(`{"type": "service-principal", "id": 123}`, string(j))
+
+	assert.Equal(PrincipalTypeGroup, PrincipalTypeOf(GroupPrincipal(1)))
+	assert.Equal(PrincipalTypeUser, PrincipalTypeOf(UserPrincipal(1)))
+}
+
+func TestBinding(t *testing.T) {
+	assert := require.New(t)
+
+	j, err := json.Marshal(IAMBinding("owner", UserPrincipal(1), GroupPrincipal(2)))
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"role": {"type": "preset", "id": "owner"},
+		"principals": [{"type": "user", "id": 1}, {"type": "group", "id": 2}]
+	}`, string(j))
+
+	j, err = json.Marshal(IDBinding("identity-admin", UserPrincipal(1)))
+	assert.NoError(err)
+	assert.JSONEq(`{
+		"role": {"type": "preset", "id": "identity-admin"},
+		"principals": [{"type": "user", "id": 1}]
+	}`, string(j))
+}
+
+func TestGrantRole(t *testing.T) {
+	assert := require.New(t)
+	u, g, sp := UserPrincipal(1), GroupPrincipal(2), ServicePrincipalPrincipal(3)
+
+	actual := GrantRole([]v1.IdPolicy{
+		IDBinding("identity-admin", u, u),
+		IDBinding("auditor", g),
+		IDBinding("identity-admin", g, u),
+	}, "identity-admin", sp, g)
+	assert.Equal([]v1.IdPolicy{
+		IDBinding("identity-admin", u, g, sp),
+		IDBinding("auditor", g),
+	}, actual)
+
+	actual2 := GrantRole[v1.IamPolicy](nil, "owner", u, u)
+	assert.Equal([]v1.IamPolicy{IAMBinding("owner", u)}, actual2)
+}
+
+func TestRevokeRole(t *testing.T) {
+	assert := require.New(t)
+	u, g := UserPrincipal(1), GroupPrincipal(2)
+
+	actual := RevokeRole([]v1.IamPolicy{
+		IAMBinding("owner", u),
+		IAMBinding("viewer", u, g),
+		IAMBinding("owner", g, u),
+	}, "owner", u)
+	assert.Equal([]v1.IamPolicy{
+		IAMBinding("viewer", u, g),
+		IAMBinding("owner", g),
+	}, actual)
+}
+
+func TestCloneBindings(t *testing.T) {
+	assert := require.New(t)
+	// no role type: cloning must not turn it into a preset
+	b := []v1.IamPolicy{{
+		Role:       v1.NewOptIamPolicyRole(v1.IamPolicyRole{ID: v1.NewOptString("x")}),
+		Principals: []v1.Principal{UserPrincipal(1)},
+	}}
+	c := CloneBindings(b)
+	assert.Equal(b, c)
+	c[0].Principals[0] = UserPrincipal(2)
+	assert.Equal(UserPrincipal(1), b[0].Principals[0])
+}
@@ -26,44 +26,6 @@ import (
 
 var theClient saclient.Client
 
-func iamrole(name string) (this v1.IamPolicyRole) {
-	this.SetType(v1.NewOptIamPolicyRoleType(v1.IamPolicyRoleTypePreset))
-	this.SetID(v1.NewOptString(name))
-	return
-}
-
-func idrole(name string) (this v1.IdPolicyRole) {
-	this.SetType(v1.NewOptIdPolicyRoleType(v1.IdPolicyRoleTypePreset))
-	this.SetID(v1.NewOptString(name))
-	return
-}
-
-func principalOf(id int) (this v1.Principal) {
-	this.SetType(v1.NewOptString("service-principal"))
-	this.SetID(v1.NewOptInt(id))
-	return
-}
-
-func iampolicy(name string, id int) (this v1.IamPolicy) {
-	var role = iamrole(name)
-	var principal = principalOf(id)
-	var principals = make([]v1.Principal, 0, 1)
-
-	this.SetRole(v1.NewOptIamPolicyRole(role))
-	this.SetPrincipals(append(principals, principal))
-	return
-}
-
-func idpolicy(name string, id int) (this v1.IdPolicy) {
-	var role = idrole(name)
-	var principal = principalOf(id)
-	var principals = make([]v1.Principal, 0, 1)
-
-	this.SetRole(v1.NewOptIdPolicyRole(role))
-	this.SetPrincipals(append(principals, principal))
-	return
-}
-
 func inspectPrincipal(p *v1.Principal) {
 	fmt.Printf("(")
 	if t, ok := p.GetType().Get(); ok {
@@ -111,7 +73,7 @@ func organizationIAMPolicies(ctx context.Context, client *v1.Client, id int) {
 		panic(err)
 	} else {
 		for _, p := range roles {
-			policies = append(policies, iampolicy(p, id))
+			policies = append(policies, iam.IAMBinding(p, iam.ServicePrincipalPrincipal(id)))
 		}
 		if actual, err := op.UpdateOrganizationPolicy(ctx, policies); err != nil {
 			panic(err)
@@ -142,7 +104,7 @@ func organizationIDPolicies(ctx context.Context, client *v1.Client, id int) {
 		panic(err)
 	} else {
 		for _, p := range roles {
-			policies = append(policies, idpolicy(p, id))
+			policies = append(policies, iam.IDBinding(p, iam.ServicePrincipalPrincipal(id)))
 		}
 		if actual, err := op.UpdateOrganizationIdPolicy(ctx, policies); err != nil {
 			panic(err)
@@ -20,3 +20,24 @@ type Error = common.Error
 
 var NewError = common.NewError
 var NewAPIError = common.NewAPIError
+
+type Problem = common.Problem
+
+var StatusCode = common.StatusCode
+var AsProblem = common.AsProblem
+
+var IsValidation = common.IsValidation
+var IsUnauthorized = common.IsUnauthorized
+var IsForbidden = common.IsForbidden
+var IsNotFound = common.IsNotFound
+var IsConflict = common.IsConflict
+var IsRateLimited = common.IsRateLimited
+var IsUnavailable = common.IsUnavailable
+
+type ValidationErrors = common.ValidationErrors
+
+const NonFieldErrors = common.NonFieldErrors
+
+var NewValidationErrors = common.NewValidationErrors
+
+var ErrConcurrentModification = common.ErrConcurrentModification
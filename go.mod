@@ -15,6 +15,8 @@ require (
 	github.com/sacloud/packages-go v0.0.12
 	github.com/sacloud/saclient-go v0.3.5
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/oauth2 v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -45,5 +47,4 @@ require (
 	golang.org/x/text v0.35.0 // indirect
 	golang.org/x/tools v0.42.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
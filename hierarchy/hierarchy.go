@@ -0,0 +1,241 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hierarchy holds the whole folder/project tree of an organization
+// in memory.
+//
+// A node is addressed by its path: the names of the folders leading to it
+// and, for a project, its code, each preceded by a slash, such as
+// "/prod/web/api-project".
+package hierarchy
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+type Kind string
+
+const (
+	KindFolder  Kind = "folder"
+	KindProject Kind = "project"
+)
+
+// Node is either a folder or a project.
+type Node struct {
+	Kind    Kind
+	Folder  *v1.Folder  // set if Kind is KindFolder
+	Project *v1.Project // set if Kind is KindProject
+
+	Parent   *Node // nil at the top
+	Children []*Node
+}
+
+func (n *Node) ID() int {
+	if n.Kind == KindProject {
+		return n.Project.ID
+	}
+	return n.Folder.ID
+}
+
+// Name is what the node is called in a path: the folder name or the project
+// code.
+func (n *Node) Name() string {
+	if n.Kind == KindProject {
+		return n.Project.Code
+	}
+	return n.Folder.Name
+}
+
+func (n *Node) Path() string {
+	names := []string{n.Name()}
+	for _, i := range n.Ancestors() {
+		names = append(names, i.Name())
+	}
+	slices.Reverse(names)
+	return "/" + strings.Join(names, "/")
+}
+
+// Ancestors lists the folders above n, nearest first.
+func (n *Node) Ancestors() []*Node {
+	var ret []*Node
+	for i := n.Parent; i != nil; i = i.Parent {
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+// Descendants lists everything below n, in the order Walk visits them.
+func (n *Node) Descendants() []*Node {
+	var ret []*Node
+	for _, c := range n.Children {
+		_ = c.Walk(func(i *Node, _ int) error {
+			ret = append(ret, i)
+			return nil
+		})
+	}
+	return ret
+}
+
+// SkipChildren can be returned by a WalkFunc to not descend into the node
+// just visited.
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called for each node with its depth, 0 for the top level.  An
+// error other than SkipChildren stops the walk and is returned by Walk.
+type WalkFunc func(n *Node, depth int) error
+
+// Walk visits n and then everything below it, depth first.
+func (n *Node) Walk(fn WalkFunc) error { return n.walk(fn, len(n.Ancestors())) }
+
+func (n *Node) walk(fn WalkFunc, depth int) error {
+	if err := fn(n, depth); errors.Is(err, SkipChildren) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, c := range n.Children {
+		if err := c.walk(fn, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tree is the folder/project hierarchy.  Children are ordered folders
+// first, then projects, each by name.
+type Tree struct {
+	Roots []*Node
+
+	folders  map[int]*Node
+	projects map[int]*Node
+}
+
+// Fetch lists every folder and project and builds the tree.
+func Fetch(ctx context.Context, folders folder.FolderAPI, projects project.ProjectAPI) (*Tree, error) {
+	f, err := folder.ListAll(ctx, folders, folder.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	p, err := project.ListAll(ctx, projects, project.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	return Build(f, p), nil
+}
+
+// Build arranges folders and projects into a tree.  Items whose parent is
+// not among folders, say because it is not visible to the caller, are put
+// at the top level.
+func Build(folders []v1.Folder, projects []v1.Project) *Tree {
+	t := Tree{
+		folders:  make(map[int]*Node, len(folders)),
+		projects: make(map[int]*Node, len(projects)),
+	}
+	for i := range folders {
+		t.folders[folders[i].ID] = &Node{Kind: KindFolder, Folder: &folders[i]}
+	}
+	for i := range projects {
+		t.projects[projects[i].ID] = &Node{Kind: KindProject, Project: &projects[i]}
+	}
+
+	attach := func(n *Node, parent v1.NilInt) {
+		if p := t.folders[parent.Value]; !parent.Null && p != nil {
+			n.Parent = p
+			p.Children = append(p.Children, n)
+		} else {
+			t.Roots = append(t.Roots, n)
+		}
+	}
+	for _, i := range folders {
+		attach(t.folders[i.ID], i.ParentID)
+	}
+	for _, i := range projects {
+		attach(t.projects[i.ID], i.ParentFolderID)
+	}
+
+	sortNodes(t.Roots)
+	for _, n := range t.folders {
+		sortNodes(n.Children)
+	}
+	return &t
+}
+
+func sortNodes(s []*Node) {
+	slices.SortFunc(s, func(a, b *Node) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind), // "folder" < "project"
+			strings.Compare(a.Name(), b.Name()),
+			cmp.Compare(a.ID(), b.ID()),
+		)
+	})
+}
+
+// Folder returns the node of the folder with the given ID, or nil.
+func (t *Tree) Folder(id int) *Node { return t.folders[id] }
+
+// Project returns the node of the project with the given ID, or nil.
+func (t *Tree) Project(id int) *Node { return t.projects[id] }
+
+// Walk visits every node, depth first.
+func (t *Tree) Walk(fn WalkFunc) error {
+	for _, n := range t.Roots {
+		if err := n.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns every node for which match is true, in the order Walk visits
+// them.
+func (t *Tree) Find(match func(*Node) bool) []*Node {
+	var ret []*Node
+	_ = t.Walk(func(n *Node, _ int) error {
+		if match(n) {
+			ret = append(ret, n)
+		}
+		return nil
+	})
+	return ret
+}
+
+// Lookup returns the node at path, like "/prod/web/api-project".  The
+// leading slash is optional.  Where a folder and a project share a name, the
+// folder wins.
+func (t *Tree) Lookup(path string) (*Node, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil, false
+	}
+
+	var cur *Node
+	level := t.Roots
+	for name := range strings.SplitSeq(path, "/") {
+		i := slices.IndexFunc(level, func(n *Node) bool { return n.Name() == name })
+		if i < 0 {
+			return nil, false
+		}
+		cur = level[i]
+		level = cur.Children
+	}
+	return cur, true
+}
@@ -0,0 +1,150 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/hierarchy"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func top() v1.NilInt         { return v1.NilInt{Null: true} }
+func under(id int) v1.NilInt { return v1.NewNilInt(id) }
+
+// /prod, /prod/web, /prod/web/api, /prod/db, /dev, /scratch
+func sample() *Tree {
+	return Build(
+		[]v1.Folder{
+			{ID: 1, Name: "prod", ParentID: top()},
+			{ID: 2, Name: "web", ParentID: under(1)},
+			{ID: 3, Name: "dev", ParentID: top()},
+		},
+		[]v1.Project{
+			{ID: 10, Code: "api", ParentFolderID: under(2)},
+			{ID: 11, Code: "db", ParentFolderID: under(1)},
+			{ID: 12, Code: "scratch", ParentFolderID: top()},
+		},
+	)
+}
+
+func paths(nodes []*Node) []string {
+	var ret []string
+	for _, n := range nodes {
+		ret = append(ret, n.Path())
+	}
+	return ret
+}
+
+func TestBuild(t *testing.T) {
+	assert := require.New(t)
+	tree := sample()
+
+	assert.Equal([]string{"/dev", "/prod", "/scratch"}, paths(tree.Roots))
+	assert.Equal([]string{"/prod/web", "/prod/db"}, paths(tree.Folder(1).Children))
+	assert.Equal("/prod/web/api", tree.Project(10).Path())
+	assert.Nil(tree.Project(1))
+}
+
+func TestBuild_Orphan(t *testing.T) {
+	assert := require.New(t)
+	tree := Build(nil, []v1.Project{{ID: 10, Code: "lost", ParentFolderID: under(99)}})
+
+	assert.Equal([]string{"/lost"}, paths(tree.Roots))
+	assert.Nil(tree.Project(10).Parent)
+}
+
+func TestWalk(t *testing.T) {
+	assert := require.New(t)
+	tree := sample()
+
+	var visited []string
+	var depths []int
+	assert.NoError(tree.Walk(func(n *Node, depth int) error {
+		visited = append(visited, n.Path())
+		depths = append(depths, depth)
+		if n.Name() == "web" {
+			return SkipChildren
+		}
+		return nil
+	}))
+	assert.Equal([]string{"/dev", "/prod", "/prod/web", "/prod/db", "/scratch"}, visited)
+	assert.Equal([]int{0, 0, 1, 1, 0}, depths)
+
+	stop := errors.New("stop")
+	assert.ErrorIs(tree.Walk(func(*Node, int) error { return stop }), stop)
+}
+
+func TestFind(t *testing.T) {
+	assert := require.New(t)
+	tree := sample()
+
+	projects := tree.Find(func(n *Node) bool { return n.Kind == KindProject })
+	assert.Equal([]string{"/prod/web/api", "/prod/db", "/scratch"}, paths(projects))
+}
+
+func TestAncestorsDescendants(t *testing.T) {
+	assert := require.New(t)
+	tree := sample()
+
+	assert.Equal([]string{"/prod/web", "/prod"}, paths(tree.Project(10).Ancestors()))
+	assert.Empty(tree.Folder(1).Ancestors())
+	assert.Equal([]string{"/prod/web", "/prod/web/api", "/prod/db"}, paths(tree.Folder(1).Descendants()))
+	assert.Empty(tree.Project(12).Descendants())
+}
+
+func TestLookup(t *testing.T) {
+	assert := require.New(t)
+	tree := sample()
+
+	n, ok := tree.Lookup("/prod/web/api")
+	assert.True(ok)
+	assert.Equal(10, n.ID())
+	assert.Equal(KindProject, n.Kind)
+
+	n, ok = tree.Lookup("prod")
+	assert.True(ok)
+	assert.Equal(1, n.ID())
+
+	for _, p := range []string{"", "/", "/prod/api", "/nowhere", "/scratch/x"} {
+		_, ok = tree.Lookup(p)
+		assert.False(ok, p)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	prod, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "prod"})
+	assert.NoError(err)
+	web, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "web", ParentID: &prod.ID})
+	assert.NoError(err)
+	_, err = iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "api", Name: "api", ParentFolderID: &web.ID})
+	assert.NoError(err)
+
+	tree, err := Fetch(ctx, iam.NewFolderOp(client), iam.NewProjectOp(client))
+	assert.NoError(err)
+	n, ok := tree.Lookup("/prod/web/api")
+	assert.True(ok)
+	assert.Equal(web.ID, n.Parent.ID())
+}
@@ -0,0 +1,360 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+var (
+	ErrNotFound       = errors.New("no such item")
+	ErrParentNotFound = errors.New("no such parent folder")
+	ErrCycle          = errors.New("would create a cycle")
+	ErrTooDeep        = errors.New("would nest folders too deep")
+	ErrDuplicateName  = errors.New("name already taken in the destination")
+	ErrConflictMove   = errors.New("moved to two different places")
+)
+
+// Move puts a folder or project under the folder ParentID, or at the top
+// level if ParentID is nil.
+type Move struct {
+	Kind     Kind
+	ID       int
+	ParentID *int
+}
+
+// MoveError is a move PlanMoves rejected.  Err is one of the Err* variables
+// of this package.
+type MoveError struct {
+	Move Move
+	Path string // of the item, before the move
+	Err  error
+
+	detail string
+}
+
+func (e *MoveError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s #%d: %s", e.Move.Kind, e.Move.ID, e.Err)
+	}
+	if e.detail == "" {
+		return fmt.Sprintf("%s %s (#%d): %s", e.Move.Kind, e.Path, e.Move.ID, e.Err)
+	}
+	return fmt.Sprintf("%s %s (#%d): %s %s", e.Move.Kind, e.Path, e.Move.ID, e.detail, e.Err)
+}
+
+func (e *MoveError) Unwrap() error { return e.Err }
+
+type MoveOptions struct {
+	// Maximum number of nested folders, the top level counting as 1.  Zero
+	// means no limit.  Projects do not count.
+	MaxDepth int
+}
+
+// MoveBatch is a single call to Folder.Move or Project.Move.
+type MoveBatch struct {
+	Kind     Kind
+	IDs      []int
+	ParentID *int
+}
+
+// MovePlan lists the calls that carry out a set of moves.  Run in order,
+// none of them passes through a state the API would reject.
+type MovePlan struct {
+	Batches []MoveBatch
+}
+
+// PlanMoves checks moves against t, as it would look once all of them are
+// done, and groups them into as few calls as it safely can.  Moves that
+// change nothing are dropped.  Moves that no order of calls can make, such
+// as two projects of the same name trading places, are rejected too.  Every
+// rejected move is reported as a *MoveError, joined into a single error.
+func (t *Tree) PlanMoves(moves []Move, opts MoveOptions) (*MovePlan, error) {
+	// parent of every folder and project afterwards, 0 for the top level
+	folders := t.parents(t.folders)
+	projects := t.parents(t.projects)
+
+	var errs []error
+	fail := func(m Move, n *Node, err error, format string, args ...any) {
+		e := &MoveError{Move: m, Err: err, detail: fmt.Sprintf(format, args...)}
+		if n != nil {
+			e.Path = n.Path()
+		}
+		errs = append(errs, e)
+	}
+
+	var pending []Move
+	dest := map[Move]int{}
+	for _, m := range moves {
+		n, to := t.node(m.Kind, m.ID), target(m)
+		key := Move{Kind: m.Kind, ID: m.ID}
+		prev, seen := dest[key]
+		dest[key] = to
+
+		switch {
+		case n == nil:
+			fail(m, nil, ErrNotFound, "")
+		case to != 0 && t.folders[to] == nil:
+			fail(m, n, ErrParentNotFound, "folder #%d:", to)
+		case seen && prev != to:
+			fail(m, n, ErrConflictMove, "")
+		case seen, parentOf(n) == to:
+			// nothing to do
+		case m.Kind == KindFolder:
+			folders[m.ID] = to
+			pending = append(pending, m)
+		default:
+			projects[m.ID] = to
+			pending = append(pending, m)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	for _, m := range pending {
+		n, to := t.node(m.Kind, m.ID), target(m)
+		if m.Kind == KindFolder && inside(folders, to, m.ID) {
+			fail(m, n, ErrCycle, "moving under %s", t.Folder(to).Path())
+			continue
+		}
+		if m.Kind == KindFolder && opts.MaxDepth > 0 {
+			if d := depth(folders, m.ID) + height(folders, m.ID) - 1; d > opts.MaxDepth {
+				fail(m, n, ErrTooDeep, "moving under %s makes %d levels of folders, which", t.path(to), d)
+				continue
+			}
+		}
+		if o := t.sibling(folders, projects, m); o != nil {
+			fail(m, n, ErrDuplicateName, "moving under %s, where %s is:", t.path(to), o.Path())
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return t.batch(pending)
+}
+
+// ApplyMoves runs the batches of plan in order and stops at the first
+// failure.  Batches already run are not undone.
+func ApplyMoves(ctx context.Context, folders folder.FolderAPI, projects project.ProjectAPI, plan *MovePlan) error {
+	for i, b := range plan.Batches {
+		var err error
+		if b.Kind == KindFolder {
+			err = folders.Move(ctx, b.IDs, b.ParentID)
+		} else {
+			err = projects.Move(ctx, b.IDs, b.ParentID)
+		}
+		if err != nil {
+			return common.NewError("Hierarchy.ApplyMoves", errors.Wrapf(err, "batch %d of %d", i+1, len(plan.Batches)))
+		}
+	}
+	return nil
+}
+
+// Moves are made in rounds, folders first.  A move joins the current round
+// unless, given the earlier rounds and the moves that already joined, it
+// would put a folder under itself, or it would meet a namesake in its new
+// parent: one there when the round starts, even if it leaves during the
+// round, or one that joined the round before.  Swapping two folders, for
+// instance, takes two rounds.  Moves that never find a round are reported.
+func (t *Tree) batch(pending []Move) (*MovePlan, error) {
+	var folders, projects []Move
+	for _, m := range pending {
+		if m.Kind == KindFolder {
+			folders = append(folders, m)
+		} else {
+			projects = append(projects, m)
+		}
+	}
+
+	f, err := t.rounds(KindFolder, folders)
+	if err != nil {
+		return nil, err
+	}
+	p, err := t.rounds(KindProject, projects)
+	if err != nil {
+		return nil, err
+	}
+	return &MovePlan{Batches: append(f, p...)}, nil
+}
+
+func (t *Tree) rounds(kind Kind, left []Move) ([]MoveBatch, error) {
+	nodes := t.folders
+	if kind == KindProject {
+		nodes = t.projects
+	}
+	current := t.parents(nodes)
+
+	var ret []MoveBatch
+	for len(left) > 0 {
+		start := maps.Clone(current)
+		var round, later []Move
+		for _, m := range left {
+			if kind == KindFolder && inside(current, target(m), m.ID) || t.namesake(nodes, start, current, m) != nil {
+				later = append(later, m)
+			} else {
+				current[m.ID] = target(m)
+				round = append(round, m)
+			}
+		}
+		if len(round) == 0 {
+			return nil, t.stuck(nodes, start, later)
+		}
+		ret = append(ret, group(kind, round)...)
+		left = later
+	}
+	return ret, nil
+}
+
+// the node with the lowest ID named like m's under m's destination, either
+// in start or in current
+func (t *Tree) namesake(nodes map[int]*Node, start, current map[int]int, m Move) *Node {
+	n, to := nodes[m.ID], target(m)
+	var ret *Node
+	for id, o := range nodes {
+		if id != m.ID && (start[id] == to || current[id] == to) && siblingName(o) == siblingName(n) {
+			if ret == nil || id < ret.ID() {
+				ret = o
+			}
+		}
+	}
+	return ret
+}
+
+// why none of moves can be made
+func (t *Tree) stuck(nodes map[int]*Node, current map[int]int, moves []Move) error {
+	var errs []error
+	for _, m := range moves {
+		n, to := nodes[m.ID], target(m)
+		e := &MoveError{Move: m, Path: n.Path(), Err: ErrCycle, detail: fmt.Sprintf("moving under %s", t.path(to))}
+		if o := t.namesake(nodes, current, current, m); o != nil {
+			e.Err, e.detail = ErrDuplicateName, fmt.Sprintf("moving under %s before %s leaves it:", t.path(to), o.Path())
+		}
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
+
+// one batch per destination, the top level first and then by folder ID
+func group(kind Kind, moves []Move) []MoveBatch {
+	var ret []MoveBatch
+	for _, m := range moves {
+		i := slices.IndexFunc(ret, func(b MoveBatch) bool { return target(Move{ParentID: b.ParentID}) == target(m) })
+		if i < 0 {
+			ret = append(ret, MoveBatch{Kind: kind, ParentID: m.ParentID})
+			i = len(ret) - 1
+		}
+		ret[i].IDs = append(ret[i].IDs, m.ID)
+	}
+	slices.SortFunc(ret, func(a, b MoveBatch) int {
+		return cmp.Compare(target(Move{ParentID: a.ParentID}), target(Move{ParentID: b.ParentID}))
+	})
+	return ret
+}
+
+func target(m Move) int {
+	if m.ParentID == nil {
+		return 0
+	}
+	return *m.ParentID
+}
+
+func parentOf(n *Node) int {
+	if n.Parent == nil {
+		return 0
+	}
+	return n.Parent.ID()
+}
+
+func (t *Tree) parents(nodes map[int]*Node) map[int]int {
+	ret := make(map[int]int, len(nodes))
+	for id, n := range nodes {
+		ret[id] = parentOf(n)
+	}
+	return ret
+}
+
+func (t *Tree) node(kind Kind, id int) *Node {
+	if kind == KindProject {
+		return t.projects[id]
+	}
+	return t.folders[id]
+}
+
+// whether folder is id or below it.  A loop that does not pass through id
+// is left for the move that closes it to report.
+func inside(parents map[int]int, folder, id int) bool {
+	for i := 0; folder != 0 && i <= len(parents); i++ {
+		if folder == id {
+			return true
+		}
+		folder = parents[folder]
+	}
+	return false
+}
+
+// levels of folders down to id, the top level being 1
+func depth(parents map[int]int, id int) int {
+	n := 0
+	for ; id != 0 && n <= len(parents); id = parents[id] {
+		n++
+	}
+	return n
+}
+
+// levels of folders from id down, id included
+func height(parents map[int]int, id int) int {
+	h := 1
+	for c, p := range parents {
+		if p == id {
+			h = max(h, 1+height(parents, c))
+		}
+	}
+	return h
+}
+
+func (t *Tree) path(folder int) string {
+	if folder == 0 {
+		return "the top level"
+	}
+	return t.folders[folder].Path()
+}
+
+// another node of m's kind with the same name under its destination, once
+// the moves are done.  The API requires names to be unique among siblings.
+func (t *Tree) sibling(folders, projects map[int]int, m Move) *Node {
+	if m.Kind == KindFolder {
+		return t.namesake(t.folders, folders, folders, m)
+	}
+	return t.namesake(t.projects, projects, projects, m)
+}
+
+// what has to be unique among the children of a folder: the name of a
+// folder or of a project
+func siblingName(n *Node) string {
+	if n.Kind == KindProject {
+		return n.Project.Name
+	}
+	return n.Folder.Name
+}
@@ -0,0 +1,184 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hierarchy_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/hierarchy"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// /a, /a/b, /a/b/c, /x, /x/b, /x/p (project), /q (project)
+func moveSample() *Tree {
+	return Build(
+		[]v1.Folder{
+			{ID: 1, Name: "a", ParentID: top()},
+			{ID: 2, Name: "b", ParentID: under(1)},
+			{ID: 3, Name: "c", ParentID: under(2)},
+			{ID: 4, Name: "x", ParentID: top()},
+			{ID: 5, Name: "b", ParentID: under(4)},
+		},
+		[]v1.Project{
+			{ID: 10, Code: "p", Name: "p", ParentFolderID: under(4)},
+			{ID: 11, Code: "q", Name: "q", ParentFolderID: top()},
+		},
+	)
+}
+
+func ptr(i int) *int { return &i }
+
+func folderTo(id, parent int) Move  { return Move{Kind: KindFolder, ID: id, ParentID: &parent} }
+func projectTo(id, parent int) Move { return Move{Kind: KindProject, ID: id, ParentID: &parent} }
+func folderToTop(id int) Move       { return Move{Kind: KindFolder, ID: id} }
+
+func TestPlanMoves_Cycle(t *testing.T) {
+	assert := require.New(t)
+
+	_, err := moveSample().PlanMoves([]Move{folderTo(1, 3)}, MoveOptions{})
+	assert.ErrorIs(err, ErrCycle)
+	assert.EqualError(err, "folder /a (#1): moving under /a/b/c would create a cycle")
+
+	var me *MoveError
+	assert.True(errors.As(err, &me))
+	assert.Equal(1, me.Move.ID)
+
+	_, err = moveSample().PlanMoves([]Move{folderTo(1, 1)}, MoveOptions{})
+	assert.ErrorIs(err, ErrCycle)
+
+	// each is fine on its own, together they form a loop
+	_, err = moveSample().PlanMoves([]Move{folderTo(1, 4), folderTo(4, 1)}, MoveOptions{})
+	assert.ErrorIs(err, ErrCycle)
+}
+
+func TestPlanMoves_Invalid(t *testing.T) {
+	assert := require.New(t)
+	tree := moveSample()
+
+	_, err := tree.PlanMoves([]Move{folderTo(99, 1)}, MoveOptions{})
+	assert.ErrorIs(err, ErrNotFound)
+	assert.EqualError(err, "folder #99: no such item")
+
+	_, err = tree.PlanMoves([]Move{projectTo(10, 99)}, MoveOptions{})
+	assert.ErrorIs(err, ErrParentNotFound)
+
+	_, err = tree.PlanMoves([]Move{folderTo(3, 1), folderTo(3, 4)}, MoveOptions{})
+	assert.ErrorIs(err, ErrConflictMove)
+
+	// /a/b/c to /x/b/c is fine, /a/b to /x is not
+	_, err = tree.PlanMoves([]Move{folderTo(3, 5), folderTo(2, 4)}, MoveOptions{})
+	assert.ErrorIs(err, ErrDuplicateName)
+	assert.EqualError(err, "folder /a/b (#2): moving under /x, where /x/b is: name already taken in the destination")
+
+	// unless /x/b leaves at the same time
+	_, err = tree.PlanMoves([]Move{folderTo(2, 4), folderToTop(5)}, MoveOptions{})
+	assert.NoError(err)
+
+	_, err = tree.PlanMoves([]Move{folderTo(4, 3)}, MoveOptions{MaxDepth: 4})
+	assert.ErrorIs(err, ErrTooDeep)
+	_, err = tree.PlanMoves([]Move{folderTo(4, 3)}, MoveOptions{MaxDepth: 5})
+	assert.NoError(err)
+
+	// every problem is reported
+	_, err = tree.PlanMoves([]Move{folderTo(99, 1), projectTo(10, 99)}, MoveOptions{})
+	assert.ErrorIs(err, ErrNotFound)
+	assert.ErrorIs(err, ErrParentNotFound)
+}
+
+func TestPlanMoves_Batches(t *testing.T) {
+	assert := require.New(t)
+	tree := moveSample()
+
+	plan, err := tree.PlanMoves([]Move{
+		projectTo(11, 4),
+		folderTo(3, 4),
+		projectTo(10, 1),
+		folderTo(2, 1), // already there
+		folderTo(3, 4), // same move twice
+	}, MoveOptions{})
+	assert.NoError(err)
+	assert.Equal([]MoveBatch{
+		{Kind: KindFolder, IDs: []int{3}, ParentID: ptr(4)},
+		{Kind: KindProject, IDs: []int{10}, ParentID: ptr(1)},
+		{Kind: KindProject, IDs: []int{11}, ParentID: ptr(4)},
+	}, plan.Batches)
+
+	// /a under /a/b/c only works once /a/b/c has left /a
+	plan, err = tree.PlanMoves([]Move{folderTo(1, 3), folderToTop(3)}, MoveOptions{})
+	assert.NoError(err)
+	assert.Equal([]MoveBatch{
+		{Kind: KindFolder, IDs: []int{3}},
+		{Kind: KindFolder, IDs: []int{1}, ParentID: ptr(3)},
+	}, plan.Batches)
+}
+
+func TestPlanMoves_NamesOnTheWay(t *testing.T) {
+	assert := require.New(t)
+	// /a/p1 and /b/p2, both named "p"
+	tree := Build(
+		[]v1.Folder{{ID: 1, Name: "a", ParentID: top()}, {ID: 2, Name: "b", ParentID: top()}},
+		[]v1.Project{
+			{ID: 10, Code: "p1", Name: "p", ParentFolderID: under(1)},
+			{ID: 11, Code: "p2", Name: "p", ParentFolderID: under(2)},
+		},
+	)
+
+	// /b/p2 has to leave before /a/p1 comes
+	plan, err := tree.PlanMoves([]Move{projectTo(10, 2), {Kind: KindProject, ID: 11}}, MoveOptions{})
+	assert.NoError(err)
+	assert.Equal([]MoveBatch{
+		{Kind: KindProject, IDs: []int{11}},
+		{Kind: KindProject, IDs: []int{10}, ParentID: ptr(2)},
+	}, plan.Batches)
+
+	// fine once done, but whichever goes first meets the other
+	_, err = tree.PlanMoves([]Move{projectTo(10, 2), projectTo(11, 1)}, MoveOptions{})
+	assert.ErrorIs(err, ErrDuplicateName)
+	assert.ErrorContains(err, "project /a/p1 (#10): moving under /b before /b/p2 leaves it: name already taken in the destination")
+}
+
+func TestApplyMoves(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	folders, projects := iam.NewFolderOp(client), iam.NewProjectOp(client)
+
+	a, err := folders.Create(ctx, folder.CreateParams{Name: "a"})
+	assert.NoError(err)
+	b, err := folders.Create(ctx, folder.CreateParams{Name: "b", ParentID: &a.ID})
+	assert.NoError(err)
+	c, err := folders.Create(ctx, folder.CreateParams{Name: "c", ParentID: &b.ID})
+	assert.NoError(err)
+	p, err := projects.Create(ctx, project.CreateParams{Code: "p", Name: "p"})
+	assert.NoError(err)
+
+	tree, err := Fetch(ctx, folders, projects)
+	assert.NoError(err)
+	plan, err := tree.PlanMoves([]Move{folderTo(a.ID, c.ID), folderToTop(c.ID), projectTo(p.ID, b.ID)}, MoveOptions{})
+	assert.NoError(err)
+	assert.NoError(ApplyMoves(ctx, folders, projects, plan))
+
+	tree, err = Fetch(ctx, folders, projects)
+	assert.NoError(err)
+	n, ok := tree.Lookup("/c/a/b/p")
+	assert.True(ok)
+	assert.Equal(p.ID, n.ID())
+}
@@ -0,0 +1,158 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keyaudit looks for service principal keys that need attention:
+// about to expire, too old, or in excess, and service principals left
+// without a working key.
+package keyaudit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+type Problem string
+
+const (
+	ProblemExpired  Problem = "expired"
+	ProblemExpiring Problem = "expiring"
+	ProblemTooOld   Problem = "too-old"
+
+	// These count the enabled keys that have not expired.
+	ProblemMultipleEnabled Problem = "multiple-enabled-keys"
+	ProblemNoEnabledKey    Problem = "no-enabled-key"
+)
+
+type Options struct {
+	// Only audit the service principals of this project.
+	ProjectID *int
+
+	// Flag keys expiring within this long.  Zero only flags expired keys.
+	ExpiringWithin time.Duration
+
+	// Flag keys created longer ago than this.  Zero disables the check.
+	MaxAge time.Duration
+
+	// The time to check against.  Defaults to now.
+	Now time.Time
+}
+
+// Finding is a problem with a service principal, or with one of its keys.
+type Finding struct {
+	Problem          Problem
+	ServicePrincipal v1.ServicePrincipal
+	Key              *v1.ServicePrincipalKey // nil for a problem of the service principal
+
+	// When the key expires, or was created for ProblemTooOld.
+	At time.Time
+}
+
+func (f Finding) String() string {
+	sp := fmt.Sprintf("service principal %s (#%d)", f.ServicePrincipal.Name, f.ServicePrincipal.ID)
+	switch f.Problem {
+	case ProblemExpired:
+		return fmt.Sprintf("%s: key %s expired at %s", sp, f.Key.Kid, f.At.Format(time.RFC3339))
+	case ProblemExpiring:
+		return fmt.Sprintf("%s: key %s expires at %s", sp, f.Key.Kid, f.At.Format(time.RFC3339))
+	case ProblemTooOld:
+		return fmt.Sprintf("%s: key %s was created at %s", sp, f.Key.Kid, f.At.Format(time.RFC3339))
+	case ProblemMultipleEnabled:
+		return sp + ": more than one unexpired enabled key"
+	case ProblemNoEnabledKey:
+		return sp + ": no unexpired enabled key"
+	default:
+		return fmt.Sprintf("%s: %s", sp, f.Problem)
+	}
+}
+
+type Auditor struct {
+	api serviceprincipal.ServicePrincipalAPI
+}
+
+func NewAuditor(client *v1.Client) *Auditor {
+	return &Auditor{api: serviceprincipal.NewServicePrincipalOp(client)}
+}
+
+// Audit lists every service principal, or those of opts.ProjectID, with
+// their keys and returns what Check finds.
+func (a *Auditor) Audit(ctx context.Context, opts Options) ([]Finding, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	sps, err := serviceprincipal.ListAll(ctx, a.api, serviceprincipal.ListParams{ProjectID: opts.ProjectID})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Finding
+	for _, sp := range sps {
+		keys, err := serviceprincipal.ListAllKeys(ctx, a.api, sp.ID, serviceprincipal.ListKeysParams{})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, Check(sp, keys, opts)...)
+	}
+	return ret, nil
+}
+
+// Check audits the keys of sp.  Only enabled keys are checked for expiry and
+// age, and dates that cannot be read are not checked at all: such a key is
+// taken to work.
+func Check(sp v1.ServicePrincipal, keys []v1.ServicePrincipalKey, opts Options) []Finding {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	var ret []Finding
+	working := 0
+	for i, k := range keys {
+		if k.Status != v1.ServicePrincipalKeyStatusEnabled {
+			continue
+		}
+		add := func(p Problem, at time.Time) {
+			ret = append(ret, Finding{Problem: p, ServicePrincipal: sp, Key: &keys[i], At: at})
+		}
+
+		expired := false
+		if s, ok := k.KeyExpiresAt.Get(); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				switch {
+				case !opts.Now.Before(t):
+					add(ProblemExpired, t)
+					expired = true
+				case opts.Now.Add(opts.ExpiringWithin).After(t):
+					add(ProblemExpiring, t)
+				}
+			}
+		}
+		if !expired {
+			working++
+		}
+		if t, err := time.Parse(time.RFC3339, k.CreatedAt); err == nil && opts.MaxAge > 0 && opts.Now.Sub(t) > opts.MaxAge {
+			add(ProblemTooOld, t)
+		}
+	}
+
+	switch {
+	case working == 0:
+		ret = append(ret, Finding{Problem: ProblemNoEnabledKey, ServicePrincipal: sp})
+	case working > 1:
+		ret = append(ret, Finding{Problem: ProblemMultipleEnabled, ServicePrincipal: sp})
+	}
+	return ret
+}
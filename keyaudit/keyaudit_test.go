@@ -0,0 +1,125 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyaudit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/keyaudit"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	projects, api := iam.NewProjectOp(client), iam.NewServicePrincipalOp(client)
+
+	web, err := projects.Create(ctx, project.CreateParams{Code: "web", Name: "web"})
+	assert.NoError(err)
+	db, err := projects.Create(ctx, project.CreateParams{Code: "db", Name: "db"})
+	assert.NoError(err)
+
+	keys := map[string]int{"single": 1, "none": 0, "double": 2}
+	for name, n := range keys {
+		sp, err := api.Create(ctx, serviceprincipal.CreateParams{ProjectID: web.ID, Name: name})
+		assert.NoError(err)
+		for range n {
+			_, err = serviceprincipal.ProvisionKey(ctx, api, sp.ID, serviceprincipal.KeyOptions{})
+			assert.NoError(err)
+		}
+	}
+	_, err = api.Create(ctx, serviceprincipal.CreateParams{ProjectID: db.ID, Name: "elsewhere"})
+	assert.NoError(err)
+
+	findings, err := NewAuditor(client).Audit(ctx, Options{ProjectID: &web.ID})
+	assert.NoError(err)
+	got := map[string][]Problem{}
+	for _, f := range findings {
+		got[f.ServicePrincipal.Name] = append(got[f.ServicePrincipal.Name], f.Problem)
+	}
+	assert.Equal(map[string][]Problem{
+		"none":   {ProblemNoEnabledKey},
+		"double": {ProblemMultipleEnabled},
+	}, got)
+
+	findings, err = NewAuditor(client).Audit(ctx, Options{MaxAge: time.Hour, Now: time.Now().Add(2 * time.Hour)})
+	assert.NoError(err)
+	got = map[string][]Problem{}
+	for _, f := range findings {
+		got[f.ServicePrincipal.Name] = append(got[f.ServicePrincipal.Name], f.Problem)
+	}
+	assert.Equal(map[string][]Problem{
+		"single":    {ProblemTooOld},
+		"none":      {ProblemNoEnabledKey},
+		"double":    {ProblemTooOld, ProblemTooOld, ProblemMultipleEnabled},
+		"elsewhere": {ProblemNoEnabledKey},
+	}, got)
+}
+
+func TestCheck(t *testing.T) {
+	assert := require.New(t)
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	sp := v1.ServicePrincipal{ID: 1, Name: "deployer"}
+	key := func(kid string, status v1.ServicePrincipalKeyStatus, created string, expires string) v1.ServicePrincipalKey {
+		return v1.ServicePrincipalKey{Kid: kid, Status: status, CreatedAt: created, KeyExpiresAt: v1.NewOptNilString(expires)}
+	}
+
+	findings := Check(sp, []v1.ServicePrincipalKey{
+		key("expired", v1.ServicePrincipalKeyStatusEnabled, "2025-01-01T00:00:00Z", "2025-05-01T00:00:00Z"),
+		key("soon", v1.ServicePrincipalKeyStatusEnabled, "2025-05-01T00:00:00Z", "2025-06-10T00:00:00Z"),
+		key("later", v1.ServicePrincipalKeyStatusEnabled, "2025-05-01T00:00:00Z", "2025-12-01T00:00:00Z"),
+		key("disabled", v1.ServicePrincipalKeyStatusDisabled, "2020-01-01T00:00:00Z", "2021-01-01T00:00:00Z"),
+	}, Options{ExpiringWithin: 30 * 24 * time.Hour, MaxAge: 90 * 24 * time.Hour, Now: now})
+
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	assert.Equal([]string{
+		"service principal deployer (#1): key expired expired at 2025-05-01T00:00:00Z",
+		"service principal deployer (#1): key expired was created at 2025-01-01T00:00:00Z",
+		"service principal deployer (#1): key soon expires at 2025-06-10T00:00:00Z",
+		"service principal deployer (#1): more than one unexpired enabled key",
+	}, got)
+
+	findings = Check(sp, []v1.ServicePrincipalKey{
+		key("disabled", v1.ServicePrincipalKeyStatusDisabled, "2025-05-01T00:00:00Z", "2025-12-01T00:00:00Z"),
+	}, Options{Now: now})
+	assert.Len(findings, 1)
+	assert.Equal(ProblemNoEnabledKey, findings[0].Problem)
+	assert.Nil(findings[0].Key)
+
+	// an expired key does not work
+	findings = Check(sp, []v1.ServicePrincipalKey{
+		key("expired", v1.ServicePrincipalKeyStatusEnabled, "2025-01-01T00:00:00Z", "2025-05-01T00:00:00Z"),
+	}, Options{Now: now})
+	assert.Len(findings, 2)
+	assert.Equal(ProblemExpired, findings[0].Problem)
+	assert.Equal(ProblemNoEnabledKey, findings[1].Problem)
+
+	findings = Check(sp, []v1.ServicePrincipalKey{
+		key("expired", v1.ServicePrincipalKeyStatusEnabled, "2025-01-01T00:00:00Z", "2025-05-01T00:00:00Z"),
+		key("later", v1.ServicePrincipalKeyStatusEnabled, "2025-05-01T00:00:00Z", "2025-12-01T00:00:00Z"),
+	}, Options{Now: now})
+	assert.Len(findings, 1)
+	assert.Equal(ProblemExpired, findings[0].Problem)
+}
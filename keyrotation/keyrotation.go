@@ -0,0 +1,375 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keyrotation replaces the keys of a service principal without
+// a moment where no key works.
+//
+// A rotation goes through these phases:
+//
+//  1. a new key is generated, saved in the State, then uploaded;
+//  2. it is used to issue a token, then handed to Options.Deploy;
+//  3. after Options.GracePeriod, the keys that were enabled before the
+//     rotation are disabled;
+//  4. after Options.DeleteAfter, they are deleted.
+//
+// Run does as much of this as it can right away and returns; call it again
+// with the same State to carry on.  The State is a small JSON document that
+// Options.Checkpoint saves after every step, so that a rotation survives
+// restarts.
+package keyrotation
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/google/uuid"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+type Phase string
+
+const (
+	PhaseNew       Phase = "new"       // nothing done yet
+	PhaseGenerated Phase = "generated" // new key generated, maybe uploaded
+	PhaseUploaded  Phase = "uploaded"  // new key uploaded, not yet verified
+	PhaseVerified  Phase = "verified"  // new key works and is deployed
+	PhaseDisabled  Phase = "disabled"  // old keys disabled
+	PhaseDone      Phase = "done"      // old keys deleted
+)
+
+// State is how far a rotation got.  It holds the private half of the new
+// key from before its upload until Deploy accepts it; keep it somewhere
+// safe.
+type State struct {
+	ServicePrincipalID int   `json:"service_principal_id"`
+	Phase              Phase `json:"phase"`
+
+	NewKeyID      uuid.UUID   `json:"new_key_id,omitzero"`
+	NewKid        string      `json:"new_kid,omitempty"`
+	PrivateKeyPEM []byte      `json:"private_key_pem,omitempty"`
+	OldKeyIDs     []uuid.UUID `json:"old_key_ids,omitempty"`
+
+	VerifiedAt time.Time `json:"verified_at,omitzero"`
+	DisabledAt time.Time `json:"disabled_at,omitzero"`
+	DeletedAt  time.Time `json:"deleted_at,omitzero"`
+}
+
+// NewState starts the rotation of the keys of the service principal id.
+func NewState(id int) *State {
+	return &State{ServicePrincipalID: id, Phase: PhaseNew}
+}
+
+func ReadState(name string) (*State, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, common.NewError("KeyRotation.ReadState", err)
+	}
+	var ret State
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, common.NewError("KeyRotation.ReadState", err)
+	}
+	return &ret, nil
+}
+
+// WriteFile saves s, readable by its owner only.
+func (s *State) WriteFile(name string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return common.NewError("KeyRotation.WriteFile", err)
+	}
+	if err := os.WriteFile(name, append(data, '\n'), 0o600); err != nil {
+		return common.NewError("KeyRotation.WriteFile", err)
+	}
+	return nil
+}
+
+type Options struct {
+	// The new key.  Defaults to a 2048 bit RSA key.
+	Key serviceprincipal.KeyOptions
+
+	// Wait between verifying the new key and disabling the old ones, for
+	// everything using them to pick up the new key.
+	GracePeriod time.Duration
+
+	// Wait between disabling the old keys and deleting them.  A disabled
+	// key can be enabled again should something still need it.
+	DeleteAfter time.Duration
+
+	// Deploy installs the new key wherever it is needed.  It is required:
+	// once it returns nil, the private key is dropped from the State and
+	// the rotation goes on.
+	Deploy func(ctx context.Context, key *serviceprincipal.ProvisionedKey) error
+
+	// Checkpoint saves the State after every step.  It is required: the new
+	// private key is in the State before it is uploaded, and a run
+	// interrupted after the upload resumes from what Checkpoint last saved.
+	// An error stops Run.
+	Checkpoint func(s *State) error
+}
+
+type Action string
+
+const (
+	ActionUpload  Action = "upload"
+	ActionVerify  Action = "verify"
+	ActionDeploy  Action = "deploy"
+	ActionDisable Action = "disable"
+	ActionDelete  Action = "delete"
+)
+
+// Step is something Run did.
+type Step struct {
+	Action Action
+	KeyID  uuid.UUID
+	Kid    string
+	At     time.Time
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("%s key %s (%s)", s.Action, s.Kid, s.KeyID)
+}
+
+// Report lists what a call to Run did.
+type Report struct {
+	Steps []Step
+	Phase Phase
+
+	// When Run can take the next step; zero once the rotation is done.
+	WaitUntil time.Time
+}
+
+func (r *Report) Done() bool { return r.Phase == PhaseDone }
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, i := range r.Steps {
+		fmt.Fprintln(&b, i)
+	}
+	if r.Done() {
+		fmt.Fprintln(&b, "Rotation done.")
+	} else {
+		fmt.Fprintf(&b, "Rotation %s, next step after %s.\n", r.Phase, r.WaitUntil.Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+type Rotator struct {
+	api  serviceprincipal.ServicePrincipalAPI
+	opts Options
+}
+
+func NewRotator(client *v1.Client, opts Options) *Rotator {
+	return &Rotator{api: serviceprincipal.NewServicePrincipalOp(client), opts: opts}
+}
+
+// Run advances s as far as the waits allow.  It updates s as it goes, so
+// that on error s still tells what was done; running again retries the
+// step that failed.
+func (r *Rotator) Run(ctx context.Context, s *State) (*Report, error) {
+	if r.opts.Deploy == nil {
+		return nil, common.NewError("KeyRotation.Run", errors.New("no Options.Deploy to hand the new key to"))
+	}
+	if r.opts.Checkpoint == nil {
+		return nil, common.NewError("KeyRotation.Run", errors.New("no Options.Checkpoint to save the state with"))
+	}
+	var ret Report
+	for {
+		now := time.Now()
+		var err error
+		switch s.Phase {
+		case PhaseNew, "":
+			err = r.generate(s)
+		case PhaseGenerated:
+			err = r.upload(ctx, s, &ret)
+		case PhaseUploaded:
+			err = r.verify(ctx, s, &ret)
+		case PhaseVerified:
+			if wait := s.VerifiedAt.Add(r.opts.GracePeriod); now.Before(wait) {
+				ret.Phase, ret.WaitUntil = s.Phase, wait
+				return &ret, nil
+			}
+			err = r.disable(ctx, s, &ret)
+		case PhaseDisabled:
+			if wait := s.DisabledAt.Add(r.opts.DeleteAfter); now.Before(wait) {
+				ret.Phase, ret.WaitUntil = s.Phase, wait
+				return &ret, nil
+			}
+			err = r.delete(ctx, s, &ret)
+		case PhaseDone:
+			ret.Phase = s.Phase
+			return &ret, nil
+		default:
+			return nil, common.NewError("KeyRotation.Run", errors.Errorf("unknown phase %q", s.Phase))
+		}
+
+		ret.Phase = s.Phase
+		if err != nil {
+			return &ret, common.NewError("KeyRotation.Run", err)
+		}
+		if err := r.opts.Checkpoint(s); err != nil {
+			return &ret, common.NewError("KeyRotation.Run", errors.Wrap(err, "checkpoint"))
+		}
+	}
+}
+
+// The key is saved in s before it is uploaded, so that a run interrupted
+// in between finds it on the service principal instead of uploading
+// another one.
+func (r *Rotator) generate(s *State) error {
+	signer, _, err := serviceprincipal.GenerateKeyPair(r.opts.Key)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return errors.Wrap(err, "generate")
+	}
+	s.PrivateKeyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	s.Phase = PhaseGenerated
+	return nil
+}
+
+func (r *Rotator) upload(ctx context.Context, s *State, report *Report) error {
+	signer, err := serviceprincipal.ParsePrivateKey(s.PrivateKeyPEM)
+	if err != nil {
+		return errors.Wrap(err, "upload")
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return errors.Wrap(err, "upload")
+	}
+
+	keys, err := serviceprincipal.ListAllKeys(ctx, r.api, s.ServicePrincipalID, serviceprincipal.ListKeysParams{})
+	if err != nil {
+		return err
+	}
+	var k *v1.ServicePrincipalKey
+	s.OldKeyIDs = nil
+	for i := range keys {
+		switch {
+		case samePublicKey(signer.Public(), keys[i].PublicKey):
+			k = &keys[i]
+		case keys[i].Status == v1.ServicePrincipalKeyStatusEnabled:
+			s.OldKeyIDs = append(s.OldKeyIDs, keys[i].ID)
+		}
+	}
+
+	if k == nil {
+		public := v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		if k, err = r.api.UploadKey(ctx, s.ServicePrincipalID, public); err != nil {
+			return errors.Wrap(err, "upload")
+		}
+	}
+	s.NewKeyID, s.NewKid = k.ID, k.Kid
+	s.Phase = PhaseUploaded
+	report.Steps = append(report.Steps, Step{Action: ActionUpload, KeyID: k.ID, Kid: k.Kid, At: time.Now()})
+	return nil
+}
+
+// whether key is the PEM encoded public key
+func samePublicKey(key crypto.PublicKey, encoded v1.ServiceprincipalKeyPublicKey) bool {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return false
+	}
+	other, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return false
+	}
+	k, ok := key.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && k.Equal(other)
+}
+
+func (r *Rotator) verify(ctx context.Context, s *State, report *Report) error {
+	signer, err := serviceprincipal.ParsePrivateKey(s.PrivateKeyPEM)
+	if err != nil {
+		return errors.Wrap(err, "verify")
+	}
+	k := &serviceprincipal.ProvisionedKey{
+		ServicePrincipalID: s.ServicePrincipalID,
+		Key:                &v1.ServicePrincipalKey{ID: s.NewKeyID, Kid: s.NewKid},
+		Signer:             signer,
+		PrivateKeyPEM:      s.PrivateKeyPEM,
+	}
+	if _, err := serviceprincipal.IssueTokenWithKey(ctx, r.api, k.AssertionParams()); err != nil {
+		return errors.Wrap(err, "verify")
+	}
+	report.Steps = append(report.Steps, Step{Action: ActionVerify, KeyID: s.NewKeyID, Kid: s.NewKid, At: time.Now()})
+
+	if err := r.opts.Deploy(ctx, k); err != nil {
+		return errors.Wrap(err, "deploy")
+	}
+	report.Steps = append(report.Steps, Step{Action: ActionDeploy, KeyID: s.NewKeyID, Kid: s.NewKid, At: time.Now()})
+	s.PrivateKeyPEM = nil
+	s.VerifiedAt = time.Now()
+	s.Phase = PhaseVerified
+	return nil
+}
+
+// Old keys that are gone or already disabled, by hand or by an interrupted
+// run, are skipped.
+func (r *Rotator) disable(ctx context.Context, s *State, report *Report) error {
+	keys, err := r.old(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k.Status != v1.ServicePrincipalKeyStatusEnabled {
+			continue
+		}
+		if _, err := r.api.DisableKey(ctx, s.ServicePrincipalID, k.ID); err != nil {
+			return errors.Wrapf(err, "disable key %s", k.Kid)
+		}
+		report.Steps = append(report.Steps, Step{Action: ActionDisable, KeyID: k.ID, Kid: k.Kid, At: time.Now()})
+	}
+	s.DisabledAt = time.Now()
+	s.Phase = PhaseDisabled
+	return nil
+}
+
+func (r *Rotator) delete(ctx context.Context, s *State, report *Report) error {
+	keys, err := r.old(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := r.api.DeleteKey(ctx, s.ServicePrincipalID, k.ID); err != nil {
+			return errors.Wrapf(err, "delete key %s", k.Kid)
+		}
+		report.Steps = append(report.Steps, Step{Action: ActionDelete, KeyID: k.ID, Kid: k.Kid, At: time.Now()})
+	}
+	s.DeletedAt = time.Now()
+	s.Phase = PhaseDone
+	return nil
+}
+
+// the old keys still there
+func (r *Rotator) old(ctx context.Context, s *State) ([]v1.ServicePrincipalKey, error) {
+	keys, err := serviceprincipal.ListAllKeys(ctx, r.api, s.ServicePrincipalID, serviceprincipal.ListKeysParams{})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(k v1.ServicePrincipalKey) bool { return !slices.Contains(s.OldKeyIDs, k.ID) }), nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyrotation_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/keyrotation"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// a service principal with one key
+func setup(t *testing.T) (*require.Assertions, *v1.Client, serviceprincipal.ServicePrincipalAPI, *serviceprincipal.ProvisionedKey) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "web", Name: "web"})
+	assert.NoError(err)
+	api := iam.NewServicePrincipalOp(client)
+	sp, err := api.Create(ctx, serviceprincipal.CreateParams{ProjectID: p.ID, Name: "deployer"})
+	assert.NoError(err)
+	k, err := serviceprincipal.ProvisionKey(ctx, api, sp.ID, serviceprincipal.KeyOptions{})
+	assert.NoError(err)
+	return assert, client, api, k
+}
+
+func keys(t *testing.T, api serviceprincipal.ServicePrincipalAPI, id int) map[string]v1.ServicePrincipalKeyStatus {
+	items, err := serviceprincipal.ListAllKeys(t.Context(), api, id, serviceprincipal.ListKeysParams{})
+	require.NoError(t, err)
+	ret := map[string]v1.ServicePrincipalKeyStatus{}
+	for _, i := range items {
+		ret[i.Kid] = i.Status
+	}
+	return ret
+}
+
+func TestRun(t *testing.T) {
+	assert, client, api, old := setup(t)
+	ctx := t.Context()
+
+	var deployed *serviceprincipal.ProvisionedKey
+	var phases []Phase
+	r := NewRotator(client, Options{
+		Deploy: func(_ context.Context, k *serviceprincipal.ProvisionedKey) error {
+			deployed = k
+			return nil
+		},
+		Checkpoint: func(s *State) error {
+			phases = append(phases, s.Phase)
+			return nil
+		},
+	})
+	s := NewState(old.ServicePrincipalID)
+	report, err := r.Run(ctx, s)
+	assert.NoError(err)
+	assert.True(report.Done())
+	assert.Equal([]Phase{PhaseGenerated, PhaseUploaded, PhaseVerified, PhaseDisabled, PhaseDone}, phases)
+
+	var actions []Action
+	for _, i := range report.Steps {
+		actions = append(actions, i.Action)
+	}
+	assert.Equal([]Action{ActionUpload, ActionVerify, ActionDeploy, ActionDisable, ActionDelete}, actions)
+	assert.Equal(old.Key.Kid, report.Steps[3].Kid)
+	assert.Contains(report.String(), "Rotation done.")
+
+	assert.NotNil(deployed)
+	assert.Equal(s.NewKid, deployed.Key.Kid)
+	assert.Empty(s.PrivateKeyPEM)
+	assert.Equal(map[string]v1.ServicePrincipalKeyStatus{s.NewKid: v1.ServicePrincipalKeyStatusEnabled}, keys(t, api, s.ServicePrincipalID))
+
+	_, err = serviceprincipal.IssueTokenWithKey(ctx, api, deployed.AssertionParams())
+	assert.NoError(err)
+
+	report, err = r.Run(ctx, s)
+	assert.NoError(err)
+	assert.True(report.Done())
+	assert.Empty(report.Steps)
+}
+
+func TestRun_Waits(t *testing.T) {
+	assert, client, api, old := setup(t)
+	ctx := t.Context()
+	file := filepath.Join(t.TempDir(), "state.json")
+	r := NewRotator(client, Options{
+		GracePeriod: time.Hour,
+		DeleteAfter: time.Hour,
+		Deploy:      func(context.Context, *serviceprincipal.ProvisionedKey) error { return nil },
+		Checkpoint:  func(s *State) error { return s.WriteFile(file) },
+	})
+
+	s := NewState(old.ServicePrincipalID)
+	report, err := r.Run(ctx, s)
+	assert.NoError(err)
+	assert.Equal(PhaseVerified, report.Phase)
+	assert.Equal(s.VerifiedAt.Add(time.Hour), report.WaitUntil)
+	assert.Len(report.Steps, 3)
+	assert.Empty(s.PrivateKeyPEM)
+	assert.Equal(v1.ServicePrincipalKeyStatusEnabled, keys(t, api, s.ServicePrincipalID)[old.Key.Kid])
+
+	// pick up from a file, once the grace period is over
+	s.VerifiedAt = s.VerifiedAt.Add(-2 * time.Hour)
+	assert.NoError(s.WriteFile(file))
+	s, err = ReadState(file)
+	assert.NoError(err)
+
+	report, err = r.Run(ctx, s)
+	assert.NoError(err)
+	assert.Equal(PhaseDisabled, report.Phase)
+	assert.Equal(ActionDisable, report.Steps[0].Action)
+	assert.Equal(v1.ServicePrincipalKeyStatusDisabled, keys(t, api, s.ServicePrincipalID)[old.Key.Kid])
+
+	s.DisabledAt = s.DisabledAt.Add(-2 * time.Hour)
+	report, err = r.Run(ctx, s)
+	assert.NoError(err)
+	assert.True(report.Done())
+	assert.NotContains(keys(t, api, s.ServicePrincipalID), old.Key.Kid)
+}
+
+func TestRun_Retry(t *testing.T) {
+	assert, client, api, old := setup(t)
+	ctx := t.Context()
+
+	fail := true
+	r := NewRotator(client, Options{
+		GracePeriod: time.Hour,
+		Deploy: func(context.Context, *serviceprincipal.ProvisionedKey) error {
+			if fail {
+				return errors.New("no such host")
+			}
+			return nil
+		},
+		Checkpoint: func(*State) error { return nil },
+	})
+	s := NewState(old.ServicePrincipalID)
+	report, err := r.Run(ctx, s)
+	assert.ErrorContains(err, "deploy: no such host")
+	assert.Equal(PhaseUploaded, report.Phase)
+	assert.NotEmpty(s.PrivateKeyPEM)
+
+	fail = false
+	report, err = r.Run(ctx, s)
+	assert.NoError(err)
+	assert.Equal(PhaseVerified, report.Phase)
+	assert.Len(keys(t, api, s.ServicePrincipalID), 2)
+}
+
+func TestRun_Required(t *testing.T) {
+	assert, client, api, old := setup(t)
+	deploy := func(context.Context, *serviceprincipal.ProvisionedKey) error { return nil }
+
+	s := NewState(old.ServicePrincipalID)
+	_, err := NewRotator(client, Options{Checkpoint: func(*State) error { return nil }}).Run(t.Context(), s)
+	assert.ErrorContains(err, "no Options.Deploy")
+	_, err = NewRotator(client, Options{Deploy: deploy}).Run(t.Context(), s)
+	assert.ErrorContains(err, "no Options.Checkpoint")
+	assert.Equal(PhaseNew, s.Phase)
+	assert.Len(keys(t, api, s.ServicePrincipalID), 1)
+}
+
+func TestRun_ResumesUpload(t *testing.T) {
+	assert, client, api, old := setup(t)
+	ctx := t.Context()
+	file := filepath.Join(t.TempDir(), "state.json")
+
+	// the process dies after the upload, before the state is saved
+	crash := errors.New("killed")
+	crashed := false
+	r := NewRotator(client, Options{
+		GracePeriod: time.Hour,
+		Deploy:      func(context.Context, *serviceprincipal.ProvisionedKey) error { return nil },
+		Checkpoint: func(s *State) error {
+			if s.Phase == PhaseUploaded && !crashed {
+				crashed = true
+				return crash
+			}
+			return s.WriteFile(file)
+		},
+	})
+	_, err := r.Run(ctx, NewState(old.ServicePrincipalID))
+	assert.ErrorIs(err, crash)
+	assert.Len(keys(t, api, old.ServicePrincipalID), 2)
+
+	s, err := ReadState(file)
+	assert.NoError(err)
+	assert.Equal(PhaseGenerated, s.Phase)
+	report, err := r.Run(ctx, s)
+	assert.NoError(err)
+	assert.Equal(PhaseVerified, report.Phase)
+
+	// the key uploaded before the crash is reused, not uploaded again
+	all := keys(t, api, s.ServicePrincipalID)
+	assert.Len(all, 2)
+	assert.Contains(all, s.NewKid)
+	assert.Equal([]uuid.UUID{old.Key.ID}, s.OldKeyIDs)
+}
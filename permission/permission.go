@@ -0,0 +1,198 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package permission answers "which IAM roles does this principal have on
+// that resource, and why".
+//
+// A binding applies to the scope it is attached to and to everything
+// below: the organization policy covers every folder and project, a folder
+// policy covers its subfolders and their projects.  Users also get the
+// roles bound to the groups they belong to.
+package permission
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/group"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/project"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+	"github.com/sacloud/iam-api-go/hierarchy"
+)
+
+// Source is where a role comes from.
+type Source struct {
+	// Scope the binding is attached to.
+	Scope iampolicy.Scope
+
+	// "organization", or the hierarchy path of the folder or project.
+	Path string
+
+	// Non-zero if the role is bound to this group rather than to the
+	// principal itself.
+	GroupID int
+}
+
+// Grant is a role a principal effectively has, with every binding that
+// grants it, the most distant scope first.
+type Grant struct {
+	Role    string
+	Sources []Source
+}
+
+// Analyzer computes effective roles.  The hierarchy, policies and group
+// memberships it reads are cached; use a new Analyzer to see later changes.
+type Analyzer struct {
+	folders  folder.FolderAPI
+	projects project.ProjectAPI
+	groups   group.GroupAPI
+	policy   iampolicy.IAMPolicyAPI
+
+	tree     *hierarchy.Tree
+	policies map[iampolicy.Scope][]v1.IamPolicy
+	members  map[int][]int
+}
+
+func NewAnalyzer(client *v1.Client) *Analyzer {
+	return &Analyzer{
+		folders:  folder.NewFolderOp(client),
+		projects: project.NewProjectOp(client),
+		groups:   group.NewGroupOp(client),
+		policy:   iampolicy.NewIAMPolicyOp(client),
+		policies: map[iampolicy.Scope][]v1.IamPolicy{},
+		members:  map[int][]int{},
+	}
+}
+
+// Effective lists the roles principal has on scope, by role ID.
+func (a *Analyzer) Effective(ctx context.Context, principal v1.Principal, scope iampolicy.Scope) ([]Grant, error) {
+	chain, err := a.chain(ctx, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Grant
+	add := func(role string, s Source) {
+		if i := slices.IndexFunc(ret, func(g Grant) bool { return g.Role == role }); i >= 0 {
+			ret[i].Sources = append(ret[i].Sources, s)
+		} else {
+			ret = append(ret, Grant{Role: role, Sources: []Source{s}})
+		}
+	}
+
+	for _, s := range chain {
+		bindings, err := a.read(ctx, s.Scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bindings {
+			role := b.Role.Value.ID.Value
+			for _, p := range b.Principals {
+				if p.Type == principal.Type && p.ID == principal.ID {
+					add(role, s)
+					continue
+				}
+				if common.PrincipalTypeOf(principal) != common.PrincipalTypeUser || common.PrincipalTypeOf(p) != common.PrincipalTypeGroup {
+					continue
+				}
+				if ok, err := a.isMember(ctx, p.ID.Value, principal.ID.Value); err != nil {
+					return nil, err
+				} else if ok {
+					add(role, Source{Scope: s.Scope, Path: s.Path, GroupID: p.ID.Value})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(ret, func(x, y Grant) int { return cmp.Compare(x.Role, y.Role) })
+	return ret, nil
+}
+
+// Has reports whether principal effectively has role on scope.
+func (a *Analyzer) Has(ctx context.Context, principal v1.Principal, scope iampolicy.Scope, role string) (bool, error) {
+	grants, err := a.Effective(ctx, principal, scope)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(grants, func(g Grant) bool { return g.Role == role }), nil
+}
+
+// the scopes whose policies apply to scope, the organization first
+func (a *Analyzer) chain(ctx context.Context, scope iampolicy.Scope) ([]Source, error) {
+	org := Source{Scope: iampolicy.OrganizationScope(), Path: string(iampolicy.ScopeOrganization)}
+	if scope.Kind == iampolicy.ScopeOrganization {
+		return []Source{org}, nil
+	}
+
+	if a.tree == nil {
+		t, err := hierarchy.Fetch(ctx, a.folders, a.projects)
+		if err != nil {
+			return nil, err
+		}
+		a.tree = t
+	}
+
+	var n *hierarchy.Node
+	switch scope.Kind {
+	case iampolicy.ScopeFolder:
+		n = a.tree.Folder(scope.ID)
+	case iampolicy.ScopeProject:
+		n = a.tree.Project(scope.ID)
+	}
+	if n == nil {
+		return nil, common.NewError("Permission.Effective", errors.Errorf("%s not found", scope))
+	}
+
+	ret := []Source{org}
+	for _, i := range slices.Backward(append([]*hierarchy.Node{n}, n.Ancestors()...)) {
+		s := iampolicy.FolderScope(i.ID())
+		if i.Kind == hierarchy.KindProject {
+			s = iampolicy.ProjectScope(i.ID())
+		}
+		ret = append(ret, Source{Scope: s, Path: i.Path()})
+	}
+	return ret, nil
+}
+
+func (a *Analyzer) read(ctx context.Context, scope iampolicy.Scope) ([]v1.IamPolicy, error) {
+	if b, ok := a.policies[scope]; ok {
+		return b, nil
+	}
+	b, err := iampolicy.ReadPolicy(ctx, a.policy, scope)
+	if err != nil {
+		return nil, err
+	}
+	a.policies[scope] = b
+	return b, nil
+}
+
+func (a *Analyzer) isMember(ctx context.Context, groupID, userID int) (bool, error) {
+	ids, ok := a.members[groupID]
+	if !ok {
+		items, err := a.groups.ReadMemberships(ctx, groupID)
+		if err != nil {
+			return false, err
+		}
+		for _, i := range items {
+			ids = append(ids, i.ID)
+		}
+		a.members[groupID] = ids
+	}
+	return slices.Contains(ids, userID), nil
+}
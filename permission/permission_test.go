@@ -0,0 +1,91 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package permission_test
+
+import (
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/permission"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEffective(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	prod, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "prod"})
+	assert.NoError(err)
+	web, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "web", ParentID: &prod.ID})
+	assert.NoError(err)
+	api, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "api", Name: "api", ParentFolderID: &web.ID})
+	assert.NoError(err)
+	other, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "other", Name: "other"})
+	assert.NoError(err)
+	alice, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "alice", Code: "alice", Password: "password"})
+	assert.NoError(err)
+	bob, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "bob", Code: "bob", Password: "password"})
+	assert.NoError(err)
+	admins, err := iam.NewGroupOp(client).Create(ctx, "admins", "")
+	assert.NoError(err)
+	_, err = iam.NewGroupOp(client).UpdateMemberships(ctx, admins.ID, []int{alice.ID})
+	assert.NoError(err)
+
+	policy := iam.NewIAMPolicyOp(client)
+	_, err = policy.UpdateOrganizationPolicy(ctx, []v1.IamPolicy{iam.IAMBinding("owner", iam.UserPrincipal(alice.ID))})
+	assert.NoError(err)
+	_, err = policy.UpdateFolderPolicy(ctx, prod.ID, []v1.IamPolicy{iam.IAMBinding("folder-admin", iam.GroupPrincipal(admins.ID), iam.UserPrincipal(bob.ID))})
+	assert.NoError(err)
+	_, err = policy.UpdateProjectPolicy(ctx, api.ID, []v1.IamPolicy{iam.IAMBinding("admin", iam.UserPrincipal(alice.ID), iam.GroupPrincipal(admins.ID))})
+	assert.NoError(err)
+
+	a := NewAnalyzer(client)
+	grants, err := a.Effective(ctx, iam.UserPrincipal(alice.ID), iampolicy.ProjectScope(api.ID))
+	assert.NoError(err)
+	assert.Equal([]Grant{
+		{Role: "admin", Sources: []Source{
+			{Scope: iampolicy.ProjectScope(api.ID), Path: "/prod/web/api"},
+			{Scope: iampolicy.ProjectScope(api.ID), Path: "/prod/web/api", GroupID: admins.ID},
+		}},
+		{Role: "folder-admin", Sources: []Source{
+			{Scope: iampolicy.FolderScope(prod.ID), Path: "/prod", GroupID: admins.ID},
+		}},
+		{Role: "owner", Sources: []Source{
+			{Scope: iampolicy.OrganizationScope(), Path: "organization"},
+		}},
+	}, grants)
+
+	grants, err = a.Effective(ctx, iam.UserPrincipal(bob.ID), iampolicy.FolderScope(web.ID))
+	assert.NoError(err)
+	assert.Equal([]Grant{{Role: "folder-admin", Sources: []Source{{Scope: iampolicy.FolderScope(prod.ID), Path: "/prod"}}}}, grants)
+
+	ok, err := a.Has(ctx, iam.UserPrincipal(bob.ID), iampolicy.ProjectScope(other.ID), "folder-admin")
+	assert.NoError(err)
+	assert.False(ok)
+
+	grants, err = a.Effective(ctx, iam.GroupPrincipal(admins.ID), iampolicy.OrganizationScope())
+	assert.NoError(err)
+	assert.Empty(grants)
+
+	_, err = a.Effective(ctx, iam.UserPrincipal(bob.ID), iampolicy.ProjectScope(12345))
+	assert.Error(err)
+}
@@ -0,0 +1,246 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policydoc is a human-editable file format for IAM and ID
+// policies, meant to be kept under version control.  Principals, roles,
+// folders and projects are written by name instead of numeric ID:
+//
+//	version: 1
+//	organization:
+//	  - role: owner
+//	    principals:
+//	      - user:alice
+//	id_policy:
+//	  - role: identity-admin
+//	    principals:
+//	      - group:admins
+//	folders:
+//	  production/web:
+//	    - role: folder-admin
+//	      principals:
+//	        - service-principal:web/deployer
+//	projects:
+//	  web:
+//	    - role: admin
+//	      principals:
+//	        - user:#123456789012
+//
+// A principal is "<type>:<name>" where name is the user code, the group
+// name or "<project code>/<name>" of a service principal.  Folders are keyed
+// by their path of names from the top, projects by code.  "#<id>" can stand
+// in for any name.  Roles are written by ID or by name.
+//
+// The same structure is accepted as JSON.  A Resolver turns a Document into
+// what the policy APIs take.
+package policydoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the only format version understood so far.
+const Version = 1
+
+// Document is a set of policies.  A nil Policy, or a folder or project not
+// listed at all, means the document does not say anything about it; an
+// empty one means it has no bindings.
+type Document struct {
+	Version      int               `json:"version" yaml:"version"`
+	Organization Policy            `json:"organization,omitzero" yaml:"organization,omitempty"`
+	IDPolicy     Policy            `json:"id_policy,omitzero" yaml:"id_policy,omitempty"`
+	Folders      map[string]Policy `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Projects     map[string]Policy `json:"projects,omitempty" yaml:"projects,omitempty"`
+}
+
+// Policy is a list of bindings, either of IAM roles or of ID roles.
+type Policy []Binding
+
+// IsZero reports whether p is absent, as opposed to empty.
+func (p Policy) IsZero() bool { return p == nil }
+
+// Binding grants Role to Principals.
+type Binding struct {
+	Role       string      `json:"role" yaml:"role"`
+	Principals []Principal `json:"principals" yaml:"principals"`
+}
+
+// Principal refers to a user, a group or a service principal by name.
+// Exactly one of Name and ID is set.
+type Principal struct {
+	Type common.PrincipalType
+
+	// User code, group name or service principal name.
+	Name string
+
+	// Code of the project a service principal belongs to.
+	Project string
+
+	ID int
+}
+
+func (p Principal) String() string {
+	switch {
+	case p.Name == "":
+		return fmt.Sprintf("%s:%s", p.Type, idRef(p.ID))
+	case p.Type == common.PrincipalTypeServicePrincipal:
+		return fmt.Sprintf("%s:%s/%s", p.Type, p.Project, p.Name)
+	default:
+		return fmt.Sprintf("%s:%s", p.Type, p.Name)
+	}
+}
+
+func (p Principal) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+func (p *Principal) UnmarshalText(b []byte) error {
+	kind, name, ok := strings.Cut(string(b), ":")
+	if !ok || name == "" {
+		return errors.Errorf("principal %q: want <type>:<name>", b)
+	}
+	ret := Principal{Type: common.PrincipalType(kind)}
+	if !knownType(ret.Type) {
+		return errors.Errorf("principal %q: unknown type %q", b, kind)
+	}
+
+	if id, ok := parseIDRef(name); ok {
+		ret.ID = id
+	} else if ret.Type == common.PrincipalTypeServicePrincipal {
+		if ret.Project, ret.Name, ok = strings.Cut(name, "/"); !ok || ret.Project == "" || ret.Name == "" {
+			return errors.Errorf("principal %q: want %s:<project code>/<name>", b, kind)
+		}
+	} else {
+		ret.Name = name
+	}
+	*p = ret
+	return nil
+}
+
+func knownType(t common.PrincipalType) bool {
+	for _, i := range t.AllValues() {
+		if i == t {
+			return true
+		}
+	}
+	return false
+}
+
+func idRef(id int) string { return "#" + strconv.Itoa(id) }
+
+func parseIDRef(s string) (int, bool) {
+	if rest, ok := strings.CutPrefix(s, "#"); ok {
+		if id, err := strconv.Atoi(rest); err == nil {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+func (d *Document) check() error {
+	if d.Version != Version {
+		return errors.Errorf("unsupported document version %d (want %d)", d.Version, Version)
+	}
+	return nil
+}
+
+// DecodeYAML reads a document written in YAML.  Unknown keys are an error.
+func DecodeYAML(r io.Reader) (*Document, error) {
+	var d Document
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&d); err != nil {
+		return nil, common.NewError("PolicyDoc.DecodeYAML", err)
+	}
+	if err := d.check(); err != nil {
+		return nil, common.NewError("PolicyDoc.DecodeYAML", err)
+	}
+	return &d, nil
+}
+
+// DecodeJSON reads a document written in JSON.  Unknown keys are an error.
+func DecodeJSON(r io.Reader) (*Document, error) {
+	var d Document
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return nil, common.NewError("PolicyDoc.DecodeJSON", err)
+	}
+	if err := d.check(); err != nil {
+		return nil, common.NewError("PolicyDoc.DecodeJSON", err)
+	}
+	return &d, nil
+}
+
+func (d *Document) EncodeYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(d); err != nil {
+		return common.NewError("PolicyDoc.EncodeYAML", err)
+	}
+	if err := enc.Close(); err != nil {
+		return common.NewError("PolicyDoc.EncodeYAML", err)
+	}
+	return nil
+}
+
+func (d *Document) EncodeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return common.NewError("PolicyDoc.EncodeJSON", err)
+	}
+	return nil
+}
+
+// ReadFile reads a document, in JSON if name ends with ".json" and in YAML
+// otherwise.
+func ReadFile(name string) (*Document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, common.NewError("PolicyDoc.ReadFile", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if isJSON(name) {
+		return DecodeJSON(f)
+	}
+	return DecodeYAML(f)
+}
+
+// WriteFile writes d in the format ReadFile expects for name.
+func (d *Document) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return common.NewError("PolicyDoc.WriteFile", err)
+	}
+	if isJSON(name) {
+		err = d.EncodeJSON(f)
+	} else {
+		err = d.EncodeYAML(f)
+	}
+	if e := f.Close(); err == nil && e != nil {
+		err = common.NewError("PolicyDoc.WriteFile", e)
+	}
+	return err
+}
+
+func isJSON(name string) bool { return strings.EqualFold(filepath.Ext(name), ".json") }
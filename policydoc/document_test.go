@@ -0,0 +1,109 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policydoc_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sacloud/iam-api-go/common"
+	. "github.com/sacloud/iam-api-go/policydoc"
+	"github.com/stretchr/testify/require"
+)
+
+const example = `version: 1
+organization:
+  - role: owner
+    principals:
+      - user:alice
+id_policy: []
+folders:
+  production/web:
+    - role: folder-admin
+      principals:
+        - group:admins
+        - service-principal:web/deployer
+projects:
+  web:
+    - role: admin
+      principals:
+        - user:#123
+`
+
+func TestDecodeYAML(t *testing.T) {
+	assert := require.New(t)
+
+	d, err := DecodeYAML(strings.NewReader(example))
+	assert.NoError(err)
+	assert.Equal(Policy{{Role: "owner", Principals: []Principal{{Type: common.PrincipalTypeUser, Name: "alice"}}}}, d.Organization)
+	assert.NotNil(d.IDPolicy)
+	assert.Empty(d.IDPolicy)
+	assert.Equal([]Principal{
+		{Type: common.PrincipalTypeGroup, Name: "admins"},
+		{Type: common.PrincipalTypeServicePrincipal, Project: "web", Name: "deployer"},
+	}, d.Folders["production/web"][0].Principals)
+	assert.Equal(Principal{Type: common.PrincipalTypeUser, ID: 123}, d.Projects["web"][0].Principals[0])
+
+	var buf bytes.Buffer
+	assert.NoError(d.EncodeYAML(&buf))
+	assert.Equal(example, buf.String())
+}
+
+func TestDecodeJSON(t *testing.T) {
+	assert := require.New(t)
+	d, err := DecodeYAML(strings.NewReader(example))
+	assert.NoError(err)
+
+	var buf bytes.Buffer
+	assert.NoError(d.EncodeJSON(&buf))
+	assert.Contains(buf.String(), `"service-principal:web/deployer"`)
+	assert.NotContains(buf.String(), `"organization": null`)
+
+	actual, err := DecodeJSON(&buf)
+	assert.NoError(err)
+	assert.Equal(d, actual)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"no version":      "organization: []",
+		"future version":  "version: 2",
+		"unknown key":     "version: 1\nowners: []",
+		"no type":         "version: 1\norganization: [{role: owner, principals: [alice]}]",
+		"unknown type":    "version: 1\norganization: [{role: owner, principals: ['robot:r2']}]",
+		"sp sans project": "version: 1\norganization: [{role: owner, principals: ['service-principal:sp']}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeYAML(strings.NewReader(doc))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	assert := require.New(t)
+	d, err := DecodeYAML(strings.NewReader(example))
+	assert.NoError(err)
+
+	for _, name := range []string{"policy.yaml", "policy.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		assert.NoError(d.WriteFile(path))
+		actual, err := ReadFile(path)
+		assert.NoError(err)
+		assert.Equal(d, actual)
+	}
+}
@@ -0,0 +1,492 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policydoc
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/group"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/iamrole"
+	"github.com/sacloud/iam-api-go/apis/idpolicy"
+	"github.com/sacloud/iam-api-go/apis/idrole"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// Resolved is a Document with every name replaced by what it stands for.
+// Nil policies and missing map entries are those the document is silent
+// about.
+type Resolved struct {
+	Organization []v1.IamPolicy
+	IDPolicy     []v1.IdPolicy
+	Folders      map[int][]v1.IamPolicy
+	Projects     map[int][]v1.IamPolicy
+}
+
+// Resolver translates between names and IDs.  Users, groups, service
+// principals, folders, projects and roles are listed once, on first use,
+// and cached; use a new Resolver to see later changes.
+type Resolver struct {
+	users             user.UserAPI
+	groups            group.GroupAPI
+	servicePrincipals serviceprincipal.ServicePrincipalAPI
+	folders           folder.FolderAPI
+	projects          project.ProjectAPI
+	iamRoles          iamrole.IAMRoleAPI
+	idRoles           idrole.IDRoleAPI
+	iamPolicy         iampolicy.IAMPolicyAPI
+	idPolicy          idpolicy.IDPolicyAPI
+
+	dir *directory
+}
+
+func NewResolver(client *v1.Client) *Resolver {
+	return &Resolver{
+		users:             user.NewUserOp(client),
+		groups:            group.NewGroupOp(client),
+		servicePrincipals: serviceprincipal.NewServicePrincipalOp(client),
+		folders:           folder.NewFolderOp(client),
+		projects:          project.NewProjectOp(client),
+		iamRoles:          iamrole.NewIAMRoleOp(client),
+		idRoles:           idrole.NewIdRoleOp(client),
+		iamPolicy:         iampolicy.NewIAMPolicyOp(client),
+		idPolicy:          idpolicy.NewIDPolicyOp(client),
+	}
+}
+
+type directory struct {
+	users             []v1.User
+	groups            []v1.Group
+	servicePrincipals []v1.ServicePrincipal
+	folders           []v1.Folder
+	projects          []v1.Project
+	iamRoles          []v1.IamRole
+	idRoles           []v1.IdRole
+}
+
+func (r *Resolver) directory(ctx context.Context) (*directory, error) {
+	if r.dir != nil {
+		return r.dir, nil
+	}
+
+	var d directory
+	var err error
+	if d.users, err = user.ListAll(ctx, r.users, user.ListParams{}); err != nil {
+		return nil, err
+	}
+	if d.groups, err = group.ListAll(ctx, r.groups, group.ListParams{}); err != nil {
+		return nil, err
+	}
+	if d.servicePrincipals, err = serviceprincipal.ListAll(ctx, r.servicePrincipals, serviceprincipal.ListParams{}); err != nil {
+		return nil, err
+	}
+	if d.folders, err = folder.ListAll(ctx, r.folders, folder.ListParams{}); err != nil {
+		return nil, err
+	}
+	if d.projects, err = project.ListAll(ctx, r.projects, project.ListParams{}); err != nil {
+		return nil, err
+	}
+	if d.iamRoles, err = iamrole.ListAll(ctx, r.iamRoles, nil, nil); err != nil {
+		return nil, err
+	}
+	if d.idRoles, err = idrole.ListAll(ctx, r.idRoles, nil, nil); err != nil {
+		return nil, err
+	}
+	r.dir = &d
+	return r.dir, nil
+}
+
+// Resolve replaces every name in d with the ID it refers to.
+func (r *Resolver) Resolve(ctx context.Context, d *Document) (*Resolved, error) {
+	var ret Resolved
+	var err error
+
+	if ret.Organization, err = r.IAMPolicy(ctx, d.Organization); err != nil {
+		return nil, errors.Wrap(err, "organization")
+	}
+	if ret.IDPolicy, err = r.IDPolicy(ctx, d.IDPolicy); err != nil {
+		return nil, errors.Wrap(err, "id_policy")
+	}
+
+	ret.Folders = make(map[int][]v1.IamPolicy, len(d.Folders))
+	for path, p := range d.Folders {
+		if p == nil {
+			continue
+		}
+		id, err := r.Folder(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if ret.Folders[id], err = r.IAMPolicy(ctx, p); err != nil {
+			return nil, errors.Wrapf(err, "folder %q", path)
+		}
+	}
+
+	ret.Projects = make(map[int][]v1.IamPolicy, len(d.Projects))
+	for code, p := range d.Projects {
+		if p == nil {
+			continue
+		}
+		id, err := r.Project(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if ret.Projects[id], err = r.IAMPolicy(ctx, p); err != nil {
+			return nil, errors.Wrapf(err, "project %q", code)
+		}
+	}
+	return &ret, nil
+}
+
+// IAMPolicy resolves p as a list of IAM role bindings.  Bindings of the same
+// role are merged and duplicate principals dropped.
+func (r *Resolver) IAMPolicy(ctx context.Context, p Policy) ([]v1.IamPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []v1.IamPolicy{}
+	for _, b := range p {
+		i := slices.IndexFunc(dir.iamRoles, func(i v1.IamRole) bool { return i.ID == b.Role })
+		if i < 0 {
+			i = slices.IndexFunc(dir.iamRoles, func(i v1.IamRole) bool { return i.Name == b.Role })
+		}
+		if i < 0 {
+			return nil, common.NewError("PolicyDoc.IAMPolicy", errors.Errorf("IAM role %q not found", b.Role))
+		}
+		principals, err := r.principals(ctx, b.Principals)
+		if err != nil {
+			return nil, err
+		}
+
+		role := dir.iamRoles[i].ID
+		if j := slices.IndexFunc(ret, func(j v1.IamPolicy) bool { return j.Role.Value.ID.Value == role }); j >= 0 {
+			ret[j].Principals = common.MergePrincipals(ret[j].Principals, principals)
+		} else {
+			ret = append(ret, common.IAMBinding(role, principals...))
+		}
+	}
+	return ret, nil
+}
+
+// IDPolicy resolves p as a list of ID role bindings.  Bindings of the same
+// role are merged and duplicate principals dropped.
+func (r *Resolver) IDPolicy(ctx context.Context, p Policy) ([]v1.IdPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []v1.IdPolicy{}
+	for _, b := range p {
+		i := slices.IndexFunc(dir.idRoles, func(i v1.IdRole) bool { return i.ID == b.Role })
+		if i < 0 {
+			i = slices.IndexFunc(dir.idRoles, func(i v1.IdRole) bool { return i.Name == b.Role })
+		}
+		if i < 0 {
+			return nil, common.NewError("PolicyDoc.IDPolicy", errors.Errorf("ID role %q not found", b.Role))
+		}
+		principals, err := r.principals(ctx, b.Principals)
+		if err != nil {
+			return nil, err
+		}
+
+		role := dir.idRoles[i].ID
+		if j := slices.IndexFunc(ret, func(j v1.IdPolicy) bool { return j.Role.Value.ID.Value == role }); j >= 0 {
+			ret[j].Principals = common.MergePrincipals(ret[j].Principals, principals)
+		} else {
+			ret = append(ret, common.IDBinding(role, principals...))
+		}
+	}
+	return ret, nil
+}
+
+func (r *Resolver) principals(ctx context.Context, p []Principal) ([]v1.Principal, error) {
+	ret := make([]v1.Principal, 0, len(p))
+	for _, i := range p {
+		q, err := r.Principal(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		ret = common.MergePrincipals(ret, []v1.Principal{q})
+	}
+	return ret, nil
+}
+
+// Principal looks up the principal p refers to.  A reference by ID is
+// checked to exist, too.  Group and service principal names need not be
+// unique: a name shared by several of them is an error, and those have to be
+// referred to by ID.
+func (r *Resolver) Principal(ctx context.Context, p Principal) (v1.Principal, error) {
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return v1.Principal{}, err
+	}
+
+	var i int
+	switch p.Type {
+	case common.PrincipalTypeUser:
+		i = slices.IndexFunc(dir.users, func(u v1.User) bool {
+			return p.Name == "" && u.ID == p.ID || p.Name != "" && u.Code == p.Name
+		})
+		if i >= 0 {
+			return common.UserPrincipal(dir.users[i].ID), nil
+		}
+	case common.PrincipalTypeGroup:
+		match := func(g v1.Group) bool {
+			return p.Name == "" && g.ID == p.ID || p.Name != "" && g.Name == p.Name
+		}
+		if count(dir.groups, match) > 1 {
+			return v1.Principal{}, common.NewError("PolicyDoc.Principal", errors.Errorf("principal %q is ambiguous, refer to it by ID", p))
+		}
+		if i = slices.IndexFunc(dir.groups, match); i >= 0 {
+			return common.GroupPrincipal(dir.groups[i].ID), nil
+		}
+	case common.PrincipalTypeServicePrincipal:
+		project := -1
+		if p.Name != "" {
+			if project, err = r.Project(ctx, p.Project); err != nil {
+				return v1.Principal{}, err
+			}
+		}
+		match := func(s v1.ServicePrincipal) bool {
+			return p.Name == "" && s.ID == p.ID || p.Name != "" && s.ProjectID == project && s.Name == p.Name
+		}
+		if count(dir.servicePrincipals, match) > 1 {
+			return v1.Principal{}, common.NewError("PolicyDoc.Principal", errors.Errorf("principal %q is ambiguous, refer to it by ID", p))
+		}
+		if i = slices.IndexFunc(dir.servicePrincipals, match); i >= 0 {
+			return common.ServicePrincipalPrincipal(dir.servicePrincipals[i].ID), nil
+		}
+	}
+	return v1.Principal{}, common.NewError("PolicyDoc.Principal", errors.Errorf("principal %q not found", p))
+}
+
+// Folder returns the ID of the folder at path, a "/" separated list of
+// folder names from the top of the hierarchy, or "#<id>".
+func (r *Resolver) Folder(ctx context.Context, path string) (int, error) {
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if id, ok := parseIDRef(path); ok {
+		if slices.ContainsFunc(dir.folders, func(f v1.Folder) bool { return f.ID == id }) {
+			return id, nil
+		}
+	} else {
+		parent, found := v1.NilInt{Null: true}, true
+		for name := range strings.SplitSeq(path, "/") {
+			i := slices.IndexFunc(dir.folders, func(f v1.Folder) bool {
+				return f.Name == name && f.ParentID.Null == parent.Null && (parent.Null || f.ParentID.Value == parent.Value)
+			})
+			if i < 0 {
+				found = false
+				break
+			}
+			parent = v1.NewNilInt(dir.folders[i].ID)
+		}
+		if found && !parent.Null {
+			return parent.Value, nil
+		}
+	}
+	return 0, common.NewError("PolicyDoc.Folder", errors.Errorf("folder %q not found", path))
+}
+
+// Project returns the ID of the project with the given code, or "#<id>".
+func (r *Resolver) Project(ctx context.Context, code string) (int, error) {
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	id, byID := parseIDRef(code)
+	if i := slices.IndexFunc(dir.projects, func(p v1.Project) bool {
+		return byID && p.ID == id || !byID && p.Code == code
+	}); i >= 0 {
+		return dir.projects[i].ID, nil
+	}
+	return 0, common.NewError("PolicyDoc.Project", errors.Errorf("project %q not found", code))
+}
+
+// Export reads every policy there is into a Document.  Folders and projects
+// without bindings are left out.
+func (r *Resolver) Export(ctx context.Context) (*Document, error) {
+	dir, err := r.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := Document{Version: Version}
+
+	if b, err := r.iamPolicy.ReadOrganizationPolicy(ctx); err != nil {
+		return nil, err
+	} else {
+		ret.Organization = r.describeIAM(b)
+	}
+	if b, err := r.idPolicy.ReadOrganizationIdPolicy(ctx); err != nil {
+		return nil, err
+	} else {
+		ret.IDPolicy = r.describeID(b)
+	}
+
+	for _, f := range dir.folders {
+		if b, err := r.iamPolicy.ReadFolderPolicy(ctx, f.ID); err != nil {
+			return nil, err
+		} else if len(b) > 0 {
+			if ret.Folders == nil {
+				ret.Folders = map[string]Policy{}
+			}
+			ret.Folders[r.folderPath(f.ID)] = r.describeIAM(b)
+		}
+	}
+	for _, p := range dir.projects {
+		if b, err := r.iamPolicy.ReadProjectPolicy(ctx, p.ID); err != nil {
+			return nil, err
+		} else if len(b) > 0 {
+			if ret.Projects == nil {
+				ret.Projects = map[string]Policy{}
+			}
+			ret.Projects[r.projectCode(p.ID)] = r.describeIAM(b)
+		}
+	}
+	return &ret, nil
+}
+
+// DescribeIAMPolicy is the reverse of IAMPolicy.  Roles are written by ID.
+func (r *Resolver) DescribeIAMPolicy(ctx context.Context, b []v1.IamPolicy) (Policy, error) {
+	if _, err := r.directory(ctx); err != nil {
+		return nil, err
+	}
+	return r.describeIAM(b), nil
+}
+
+// DescribeIDPolicy is the reverse of IDPolicy.  Roles are written by ID.
+func (r *Resolver) DescribeIDPolicy(ctx context.Context, b []v1.IdPolicy) (Policy, error) {
+	if _, err := r.directory(ctx); err != nil {
+		return nil, err
+	}
+	return r.describeID(b), nil
+}
+
+func (r *Resolver) describeIAM(b []v1.IamPolicy) Policy {
+	ret := make(Policy, 0, len(b))
+	for _, i := range b {
+		ret = append(ret, Binding{Role: i.Role.Value.ID.Value, Principals: r.describePrincipals(i.Principals)})
+	}
+	return ret
+}
+
+func (r *Resolver) describeID(b []v1.IdPolicy) Policy {
+	ret := make(Policy, 0, len(b))
+	for _, i := range b {
+		ret = append(ret, Binding{Role: i.Role.Value.ID.Value, Principals: r.describePrincipals(i.Principals)})
+	}
+	return ret
+}
+
+func (r *Resolver) describePrincipals(p []v1.Principal) []Principal {
+	ret := make([]Principal, 0, len(p))
+	for _, i := range p {
+		ret = append(ret, r.describePrincipal(i))
+	}
+	return ret
+}
+
+// DescribePrincipal is the reverse of Principal.  Principals not found, and
+// those whose name is shared by another, are referred to by ID.
+func (r *Resolver) DescribePrincipal(ctx context.Context, p v1.Principal) (Principal, error) {
+	if _, err := r.directory(ctx); err != nil {
+		return Principal{}, err
+	}
+	return r.describePrincipal(p), nil
+}
+
+func (r *Resolver) describePrincipal(p v1.Principal) Principal {
+	ret := Principal{Type: common.PrincipalTypeOf(p), ID: p.ID.Value}
+
+	switch ret.Type {
+	case common.PrincipalTypeUser:
+		if i := indexByID(r.dir.users, ret.ID, (*v1.User).GetID); i >= 0 {
+			ret.Name, ret.ID = r.dir.users[i].Code, 0
+		}
+	case common.PrincipalTypeGroup:
+		if i := indexByID(r.dir.groups, ret.ID, (*v1.Group).GetID); i >= 0 {
+			g := r.dir.groups[i]
+			if count(r.dir.groups, func(h v1.Group) bool { return h.Name == g.Name }) == 1 {
+				ret.Name, ret.ID = g.Name, 0
+			}
+		}
+	case common.PrincipalTypeServicePrincipal:
+		if i := indexByID(r.dir.servicePrincipals, ret.ID, (*v1.ServicePrincipal).GetID); i >= 0 {
+			s := r.dir.servicePrincipals[i]
+			unique := count(r.dir.servicePrincipals, func(t v1.ServicePrincipal) bool { return t.ProjectID == s.ProjectID && t.Name == s.Name }) == 1
+			if code := r.projectCode(s.ProjectID); unique && !strings.HasPrefix(code, "#") && !strings.Contains(code, "/") {
+				ret.Name, ret.Project, ret.ID = s.Name, code, 0
+			}
+		}
+	}
+	return ret
+}
+
+// the path Folder would resolve to id, or "#<id>" if there is none
+func (r *Resolver) folderPath(id int) string {
+	var names []string
+	for cur := v1.NewNilInt(id); !cur.Null; {
+		i := indexByID(r.dir.folders, cur.Value, (*v1.Folder).GetID)
+		if i < 0 || strings.Contains(r.dir.folders[i].Name, "/") {
+			return idRef(id)
+		}
+		names = append(names, r.dir.folders[i].Name)
+		cur = r.dir.folders[i].ParentID
+	}
+	slices.Reverse(names)
+	return strings.Join(names, "/")
+}
+
+func (r *Resolver) projectCode(id int) string {
+	if i := indexByID(r.dir.projects, id, (*v1.Project).GetID); i >= 0 {
+		return r.dir.projects[i].Code
+	}
+	return idRef(id)
+}
+
+func indexByID[T any](s []T, id int, get func(*T) int) int {
+	return slices.IndexFunc(s, func(t T) bool { return get(&t) == id })
+}
+
+func count[T any](s []T, match func(T) bool) int {
+	n := 0
+	for _, t := range s {
+		if match(t) {
+			n++
+		}
+	}
+	return n
+}
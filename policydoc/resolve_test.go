@@ -0,0 +1,202 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policydoc_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/policydoc"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type world struct {
+	client          *v1.Client
+	alice, bob      *v1.User
+	admins          *v1.Group
+	production, web *v1.Folder
+	project         *v1.Project
+	deployer        *v1.ServicePrincipal
+}
+
+// populates the fake server with what example refers to
+func setup(t *testing.T) (*require.Assertions, *world) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	w := world{client: client}
+	var err error
+
+	w.alice, err = iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "Alice", Code: "alice", Password: "password"})
+	assert.NoError(err)
+	w.bob, err = iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "Bob", Code: "bob", Password: "password"})
+	assert.NoError(err)
+	w.admins, err = iam.NewGroupOp(client).Create(ctx, "admins", "")
+	assert.NoError(err)
+	w.production, err = iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "production"})
+	assert.NoError(err)
+	w.web, err = iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "web", ParentID: &w.production.ID})
+	assert.NoError(err)
+	w.project, err = iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "web", Name: "web", ParentFolderID: &w.web.ID})
+	assert.NoError(err)
+	w.deployer, err = iam.NewServicePrincipalOp(client).Create(ctx, serviceprincipal.CreateParams{ProjectID: w.project.ID, Name: "deployer"})
+	assert.NoError(err)
+	return assert, &w
+}
+
+func TestResolve(t *testing.T) {
+	assert, w := setup(t)
+	d, err := DecodeYAML(strings.NewReader(strings.ReplaceAll(example, "#123", fmt.Sprintf("#%d", w.bob.ID))))
+	assert.NoError(err)
+
+	actual, err := NewResolver(w.client).Resolve(t.Context(), d)
+	assert.NoError(err)
+	assert.Equal(&Resolved{
+		Organization: []v1.IamPolicy{iam.IAMBinding("owner", iam.UserPrincipal(w.alice.ID))},
+		IDPolicy:     []v1.IdPolicy{},
+		Folders: map[int][]v1.IamPolicy{
+			w.web.ID: {iam.IAMBinding("folder-admin", iam.GroupPrincipal(w.admins.ID), iam.ServicePrincipalPrincipal(w.deployer.ID))},
+		},
+		Projects: map[int][]v1.IamPolicy{
+			w.project.ID: {iam.IAMBinding("admin", iam.UserPrincipal(w.bob.ID))},
+		},
+	}, actual)
+}
+
+func TestResolve_Merge(t *testing.T) {
+	assert, w := setup(t)
+	d, err := DecodeYAML(strings.NewReader(`version: 1
+organization:
+  - role: owner
+    principals: [user:alice, user:bob, user:alice]
+  - role: owner
+    principals: [user:bob, group:admins]
+`))
+	assert.NoError(err)
+
+	actual, err := NewResolver(w.client).Resolve(t.Context(), d)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{
+		iam.IAMBinding("owner", iam.UserPrincipal(w.alice.ID), iam.UserPrincipal(w.bob.ID), iam.GroupPrincipal(w.admins.ID)),
+	}, actual.Organization)
+	assert.Nil(actual.IDPolicy)
+}
+
+func TestResolve_Silent(t *testing.T) {
+	assert, w := setup(t)
+	d, err := DecodeYAML(strings.NewReader(`version: 1
+folders:
+  production:
+  production/web: []
+projects:
+  web:
+`))
+	assert.NoError(err)
+
+	actual, err := NewResolver(w.client).Resolve(t.Context(), d)
+	assert.NoError(err)
+	assert.Equal(map[int][]v1.IamPolicy{w.web.ID: {}}, actual.Folders)
+	assert.Empty(actual.Projects)
+}
+
+func TestResolve_RoleByName(t *testing.T) {
+	assert, w := setup(t)
+	roles, err := iam.NewIAMRoleOp(w.client).Read(t.Context(), "folder-admin")
+	assert.NoError(err)
+
+	actual, err := NewResolver(w.client).IAMPolicy(t.Context(), Policy{{
+		Role:       roles.Name,
+		Principals: []Principal{{Type: iam.PrincipalTypeUser, Name: "alice"}},
+	}})
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("folder-admin", iam.UserPrincipal(w.alice.ID))}, actual)
+}
+
+func TestResolve_NotFound(t *testing.T) {
+	for name, doc := range map[string]string{
+		"user":              "organization: [{role: owner, principals: ['user:carol']}]",
+		"user by id":        "organization: [{role: owner, principals: ['user:#1']}]",
+		"group":             "organization: [{role: owner, principals: ['group:nobody']}]",
+		"service principal": "organization: [{role: owner, principals: ['service-principal:web/nobody']}]",
+		"sp project":        "organization: [{role: owner, principals: ['service-principal:api/deployer']}]",
+		"role":              "organization: [{role: janitor, principals: ['user:alice']}]",
+		"id role":           "id_policy: [{role: owner, principals: ['user:alice']}]",
+		"folder":            "folders: {web: []}",
+		"folder path":       "folders: {production/api: []}",
+		"project":           "projects: {api: []}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert, w := setup(t)
+			d, err := DecodeYAML(strings.NewReader("version: 1\n" + doc))
+			assert.NoError(err)
+			_, err = NewResolver(w.client).Resolve(t.Context(), d)
+			assert.Error(err)
+		})
+	}
+}
+
+func TestResolve_Ambiguous(t *testing.T) {
+	assert := require.New(t)
+	client, srv := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	ids := []int{srv.AddGroup("ops"), srv.AddGroup("ops")}
+
+	r := NewResolver(client)
+	_, err := r.Principal(ctx, Principal{Type: "group", Name: "ops"})
+	assert.ErrorContains(err, "ambiguous")
+
+	for _, id := range ids {
+		p, err := r.DescribePrincipal(ctx, iam.GroupPrincipal(id))
+		assert.NoError(err)
+		assert.Equal(fmt.Sprintf("group:#%d", id), p.String())
+		q, err := r.Principal(ctx, p)
+		assert.NoError(err)
+		assert.Equal(id, q.ID.Value)
+	}
+}
+
+func TestExport(t *testing.T) {
+	assert, w := setup(t)
+	d, err := DecodeYAML(strings.NewReader(strings.ReplaceAll(example, "#123", fmt.Sprintf("#%d", w.bob.ID))))
+	assert.NoError(err)
+	resolved, err := NewResolver(w.client).Resolve(t.Context(), d)
+	assert.NoError(err)
+
+	api := iam.NewIAMPolicyOp(w.client)
+	_, err = api.UpdateOrganizationPolicy(t.Context(), resolved.Organization)
+	assert.NoError(err)
+	for id, b := range resolved.Folders {
+		_, err = api.UpdateFolderPolicy(t.Context(), id, b)
+		assert.NoError(err)
+	}
+	for id, b := range resolved.Projects {
+		_, err = api.UpdateProjectPolicy(t.Context(), id, b)
+		assert.NoError(err)
+	}
+
+	actual, err := NewResolver(w.client).Export(t.Context())
+	assert.NoError(err)
+	expected, err := DecodeYAML(strings.NewReader(strings.ReplaceAll(example, "user:#123", "user:bob")))
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+}
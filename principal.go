@@ -0,0 +1,35 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iam
+
+import "github.com/sacloud/iam-api-go/common"
+
+type PrincipalType = common.PrincipalType
+
+const (
+	PrincipalTypeUser             = common.PrincipalTypeUser
+	PrincipalTypeGroup            = common.PrincipalTypeGroup
+	PrincipalTypeServicePrincipal = common.PrincipalTypeServicePrincipal
+)
+
+var PrincipalTypeOf = common.PrincipalTypeOf
+var UserPrincipal = common.UserPrincipal
+var GroupPrincipal = common.GroupPrincipal
+var ServicePrincipalPrincipal = common.ServicePrincipalPrincipal
+
+var PresetIAMRole = common.PresetIAMRole
+var PresetIDRole = common.PresetIDRole
+var IAMBinding = common.IAMBinding
+var IDBinding = common.IDBinding
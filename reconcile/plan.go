@@ -0,0 +1,120 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionMove   Action = "move"
+	ActionDelete Action = "delete"
+)
+
+func (a Action) symbol() string {
+	switch a {
+	case ActionCreate:
+		return "+"
+	case ActionDelete:
+		return "-"
+	case ActionMove:
+		return ">"
+	default:
+		return "~"
+	}
+}
+
+type Kind string
+
+const (
+	KindUser             Kind = "user"
+	KindGroup            Kind = "group"
+	KindMembership       Kind = "membership"
+	KindFolder           Kind = "folder"
+	KindProject          Kind = "project"
+	KindServicePrincipal Kind = "service-principal"
+	KindIAMPolicy        Kind = "iam-policy"
+	KindIDPolicy         Kind = "id-policy"
+)
+
+// Change is one step of a Plan.
+type Change struct {
+	Action Action
+	Kind   Kind
+
+	// What the change is about, named the way State names it.
+	Name string
+
+	// Human readable description of what changes, one line each.
+	Details []string
+
+	apply func(ctx context.Context, a *applier) error
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %q", c.Action, c.Kind, c.Name)
+}
+
+// Plan is the ordered list of changes that bring the organization to the
+// desired State.  It is only valid as long as nobody else changes the
+// organization.
+type Plan struct {
+	Changes []Change
+
+	live *snapshot
+}
+
+// Empty reports whether the organization is already as desired.
+func (p *Plan) Empty() bool { return len(p.Changes) == 0 }
+
+// Count returns how many changes of the given action the plan holds.
+func (p *Plan) Count(action Action) (n int) {
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return
+}
+
+// String renders the plan for humans: a line per change, marked "+" for
+// create, "~" for update, ">" for move and "-" for delete, then a summary
+// such as "Plan: 1 to create, 1 to update, 0 to move, 1 to delete.".
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes.\n"
+	}
+
+	var b strings.Builder
+	for _, c := range p.Changes {
+		fmt.Fprintf(&b, "%s %s %q\n", c.Action.symbol(), c.Kind, c.Name)
+		for _, d := range c.Details {
+			fmt.Fprintf(&b, "    %s\n", d)
+		}
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to move, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionMove), p.Count(ActionDelete))
+	return b.String()
+}
+
+func changed(field string, from, to string) string {
+	return fmt.Sprintf("%s: %q => %q", field, from, to)
+}
@@ -0,0 +1,871 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/group"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/iamrole"
+	"github.com/sacloud/iam-api-go/apis/idpolicy"
+	"github.com/sacloud/iam-api-go/apis/idrole"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+	"github.com/sacloud/iam-api-go/policydoc"
+)
+
+type Reconciler struct {
+	client            *v1.Client
+	users             user.UserAPI
+	groups            group.GroupAPI
+	folders           folder.FolderAPI
+	projects          project.ProjectAPI
+	servicePrincipals serviceprincipal.ServicePrincipalAPI
+	iamRoles          iamrole.IAMRoleAPI
+	idRoles           idrole.IDRoleAPI
+	iamPolicy         iampolicy.IAMPolicyAPI
+	idPolicy          idpolicy.IDPolicyAPI
+}
+
+func NewReconciler(client *v1.Client) *Reconciler {
+	return &Reconciler{
+		client:            client,
+		users:             user.NewUserOp(client),
+		groups:            group.NewGroupOp(client),
+		folders:           folder.NewFolderOp(client),
+		projects:          project.NewProjectOp(client),
+		servicePrincipals: serviceprincipal.NewServicePrincipalOp(client),
+		iamRoles:          iamrole.NewIAMRoleOp(client),
+		idRoles:           idrole.NewIdRoleOp(client),
+		iamPolicy:         iampolicy.NewIAMPolicyOp(client),
+		idPolicy:          idpolicy.NewIDPolicyOp(client),
+	}
+}
+
+type spKey struct{ project, name string }
+
+func (k spKey) String() string { return k.project + "/" + k.name }
+
+// what the organization looked like when planning, keyed the way State is
+type snapshot struct {
+	users             map[string]v1.User
+	groups            map[string]v1.Group
+	folders           map[string]v1.Folder
+	projects          map[string]v1.Project
+	servicePrincipals map[spKey]v1.ServicePrincipal
+	iamRoles          []v1.IamRole
+	idRoles           []v1.IdRole
+
+	folderPaths  map[int]string
+	projectCodes map[int]string
+}
+
+func (r *Reconciler) snapshot(ctx context.Context) (*snapshot, error) {
+	users, err := user.ListAll(ctx, r.users, user.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	groups, err := group.ListAll(ctx, r.groups, group.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	folders, err := folder.ListAll(ctx, r.folders, folder.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	projects, err := project.ListAll(ctx, r.projects, project.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+	sps, err := serviceprincipal.ListAll(ctx, r.servicePrincipals, serviceprincipal.ListParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	s := snapshot{
+		users:             map[string]v1.User{},
+		groups:            map[string]v1.Group{},
+		folders:           map[string]v1.Folder{},
+		projects:          map[string]v1.Project{},
+		servicePrincipals: map[spKey]v1.ServicePrincipal{},
+		folderPaths:       map[int]string{},
+		projectCodes:      map[int]string{},
+	}
+	if s.iamRoles, err = iamrole.ListAll(ctx, r.iamRoles, nil, nil); err != nil {
+		return nil, err
+	}
+	if s.idRoles, err = idrole.ListAll(ctx, r.idRoles, nil, nil); err != nil {
+		return nil, err
+	}
+
+	for _, u := range users {
+		s.users[u.Code] = u
+	}
+	for _, g := range groups {
+		s.groups[g.Name] = g
+	}
+	byID := make(map[int]v1.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+	// a folder whose parent is not visible is taken for a top level one, as
+	// hierarchy.Build does
+	for _, f := range folders {
+		var names []string
+		for cur, ok := f, true; ok; {
+			names = append(names, cur.Name)
+			if cur.ParentID.Null || len(names) > len(folders) {
+				break
+			}
+			cur, ok = byID[cur.ParentID.Value]
+		}
+		slices.Reverse(names)
+		p := strings.Join(names, "/")
+		s.folders[p] = f
+		s.folderPaths[f.ID] = p
+	}
+	for _, p := range projects {
+		s.projects[p.Code] = p
+		s.projectCodes[p.ID] = p.Code
+	}
+	for _, i := range sps {
+		s.servicePrincipals[spKey{s.projectCodes[i.ProjectID], i.Name}] = i
+	}
+	return &s, nil
+}
+
+// how Plan writes p, by name whenever possible
+func (s *snapshot) describe(p v1.Principal) string {
+	ret := policydoc.Principal{Type: common.PrincipalTypeOf(p), ID: p.ID.Value}
+	switch ret.Type {
+	case common.PrincipalTypeUser:
+		for code, u := range s.users {
+			if u.ID == ret.ID {
+				ret.Name, ret.ID = code, 0
+			}
+		}
+	case common.PrincipalTypeGroup:
+		for name, g := range s.groups {
+			if g.ID == ret.ID {
+				ret.Name, ret.ID = name, 0
+			}
+		}
+	case common.PrincipalTypeServicePrincipal:
+		for k, i := range s.servicePrincipals {
+			if i.ID == ret.ID {
+				ret.Project, ret.Name, ret.ID = k.project, k.name, 0
+			}
+		}
+	}
+	return ret.String()
+}
+
+func (s *snapshot) parentPath(p v1.Project) string {
+	if p.ParentFolderID.Null {
+		return ""
+	}
+	return s.folderPaths[p.ParentFolderID.Value]
+}
+
+// Plan computes the changes that turn the organization into desired.
+// Nothing is written.
+func (r *Reconciler) Plan(ctx context.Context, desired *State, opts Options) (*Plan, error) {
+	live, err := r.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := planner{r: r, live: live, desired: desired, plan: &Plan{live: live}}
+	if err := p.index(); err != nil {
+		return nil, common.NewError("Reconcile.Plan", err)
+	}
+
+	for _, step := range []func(context.Context) error{
+		p.planFolders,
+		p.planProjects,
+		p.planUsers,
+		p.planGroups,
+		p.planMemberships,
+		p.planServicePrincipals,
+		p.planPolicies,
+	} {
+		if err := step(ctx); err != nil {
+			return nil, common.NewError("Reconcile.Plan", err)
+		}
+	}
+	if opts.Prune {
+		p.planDeletions()
+	}
+	return p.plan, nil
+}
+
+type planner struct {
+	r       *Reconciler
+	live    *snapshot
+	desired *State
+	plan    *Plan
+
+	// every folder that should exist, implicit ancestors included
+	folders map[string]*Folder
+
+	users             map[string]bool
+	groups            map[string]bool
+	projects          map[string]bool
+	servicePrincipals map[spKey]bool
+}
+
+func (p *planner) add(c Change) { p.plan.Changes = append(p.plan.Changes, c) }
+
+// indexes desired and rejects duplicates
+func (p *planner) index() error {
+	p.folders = map[string]*Folder{}
+	for i := range p.desired.Folders {
+		f := &p.desired.Folders[i]
+		if f.Path == "" || slices.Contains(strings.Split(f.Path, "/"), "") {
+			return errors.Errorf("invalid folder path %q", f.Path)
+		}
+		if p.folders[f.Path] != nil {
+			return errors.Errorf("folder %q is declared twice", f.Path)
+		}
+		p.folders[f.Path] = f
+	}
+	for i := range p.desired.Folders {
+		for dir := path.Dir(p.desired.Folders[i].Path); dir != "."; dir = path.Dir(dir) {
+			if p.folders[dir] == nil {
+				p.folders[dir] = &Folder{Path: dir}
+			}
+		}
+	}
+
+	var err error
+	if p.users, err = keys(p.desired.Users, "user", func(u User) string { return u.Code }); err != nil {
+		return err
+	}
+	if p.groups, err = keys(p.desired.Groups, "group", func(g Group) string { return g.Name }); err != nil {
+		return err
+	}
+	if p.projects, err = keys(p.desired.Projects, "project", func(i Project) string { return i.Code }); err != nil {
+		return err
+	}
+	p.servicePrincipals, err = keys(p.desired.ServicePrincipals, "service principal", func(i ServicePrincipal) spKey {
+		return spKey{i.Project, i.Name}
+	})
+	return err
+}
+
+func keys[T any, K comparable](s []T, what string, key func(T) K) (map[K]bool, error) {
+	ret := make(map[K]bool, len(s))
+	for _, i := range s {
+		k := key(i)
+		if ret[k] {
+			return nil, errors.Errorf("%s %v is declared twice", what, k)
+		}
+		ret[k] = true
+	}
+	return ret, nil
+}
+
+func (p *planner) folderExists(path string) bool {
+	_, live := p.live.folders[path]
+	return path == "" || live || p.folders[path] != nil
+}
+
+func depth(path string) int { return strings.Count(path, "/") }
+
+// parents before children
+func byDepth(a, b string) int {
+	return cmp.Or(cmp.Compare(depth(a), depth(b)), cmp.Compare(a, b))
+}
+
+func (p *planner) planFolders(context.Context) error {
+	for _, fp := range slices.SortedFunc(maps.Keys(p.folders), byDepth) {
+		f := p.folders[fp]
+		cur, ok := p.live.folders[fp]
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindFolder,
+				Name:    fp,
+				Details: nonEmpty("description", f.Description),
+				apply: func(ctx context.Context, a *applier) error {
+					params := folder.CreateParams{Name: path.Base(fp), Description: &f.Description}
+					if dir := path.Dir(fp); dir != "." {
+						params.ParentID = ptr(a.folders[dir])
+					}
+					ret, err := a.r.folders.Create(ctx, params)
+					if err == nil {
+						a.folders[fp] = ret.ID
+					}
+					return err
+				},
+			})
+		} else if cur.Description != f.Description && slices.ContainsFunc(p.desired.Folders, func(i Folder) bool { return i.Path == fp }) {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindFolder,
+				Name:    fp,
+				Details: []string{changed("description", cur.Description, f.Description)},
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.folders.Update(ctx, cur.ID, cur.Name, &f.Description)
+					return err
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planProjects(context.Context) error {
+	for _, d := range p.desired.Projects {
+		if !p.folderExists(d.Folder) {
+			return errors.Errorf("project %q: folder %q is not declared", d.Code, d.Folder)
+		}
+
+		cur, ok := p.live.projects[d.Code]
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindProject,
+				Name:    d.Code,
+				Details: slices.Concat(nonEmpty("name", d.Name), nonEmpty("description", d.Description), nonEmpty("folder", d.Folder)),
+				apply: func(ctx context.Context, a *applier) error {
+					ret, err := a.r.projects.Create(ctx, project.CreateParams{
+						Code:           d.Code,
+						Name:           d.Name,
+						Description:    d.Description,
+						ParentFolderID: a.folderID(d.Folder),
+					})
+					if err == nil {
+						a.projects[d.Code] = ret.ID
+					}
+					return err
+				},
+			})
+			continue
+		}
+
+		var details []string
+		if cur.Name != d.Name {
+			details = append(details, changed("name", cur.Name, d.Name))
+		}
+		if cur.Description != d.Description {
+			details = append(details, changed("description", cur.Description, d.Description))
+		}
+		if details != nil {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindProject,
+				Name:    d.Code,
+				Details: details,
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.projects.Update(ctx, cur.ID, d.Name, d.Description)
+					return err
+				},
+			})
+		}
+		if from := p.live.parentPath(cur); from != d.Folder {
+			p.add(Change{
+				Action:  ActionMove,
+				Kind:    KindProject,
+				Name:    d.Code,
+				Details: []string{changed("folder", from, d.Folder)},
+				apply: func(ctx context.Context, a *applier) error {
+					return a.r.projects.Move(ctx, []int{cur.ID}, a.folderID(d.Folder))
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planUsers(context.Context) error {
+	for _, d := range p.desired.Users {
+		cur, ok := p.live.users[d.Code]
+		if !ok {
+			if d.Password == "" {
+				return errors.Errorf("user %q: a password is needed to create it", d.Code)
+			}
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindUser,
+				Name:    d.Code,
+				Details: slices.Concat(nonEmpty("name", d.Name), nonEmpty("description", d.Description), nonEmpty("email", d.Email)),
+				apply: func(ctx context.Context, a *applier) error {
+					params := user.CreateParams{Code: d.Code, Name: d.Name, Description: d.Description, Password: d.Password}
+					if d.Email != "" {
+						params.Email = &d.Email
+					}
+					ret, err := a.r.users.Create(ctx, params)
+					if err == nil {
+						a.users[d.Code] = ret.ID
+					}
+					return err
+				},
+			})
+			continue
+		}
+
+		var details []string
+		if cur.Name != d.Name {
+			details = append(details, changed("name", cur.Name, d.Name))
+		}
+		if cur.Description != d.Description {
+			details = append(details, changed("description", cur.Description, d.Description))
+		}
+		if details != nil {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindUser,
+				Name:    d.Code,
+				Details: details,
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.users.Update(ctx, cur.ID, user.UpdateParams{Name: d.Name, Description: d.Description})
+					return err
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planGroups(context.Context) error {
+	for _, d := range p.desired.Groups {
+		cur, ok := p.live.groups[d.Name]
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindGroup,
+				Name:    d.Name,
+				Details: nonEmpty("description", d.Description),
+				apply: func(ctx context.Context, a *applier) error {
+					ret, err := a.r.groups.Create(ctx, d.Name, d.Description)
+					if err == nil {
+						a.groups[d.Name] = ret.ID
+					}
+					return err
+				},
+			})
+		} else if cur.Description != d.Description {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindGroup,
+				Name:    d.Name,
+				Details: []string{changed("description", cur.Description, d.Description)},
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.groups.Update(ctx, cur.ID, d.Name, d.Description)
+					return err
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planMemberships(ctx context.Context) error {
+	for _, d := range p.desired.Groups {
+		if d.Members == nil {
+			continue
+		}
+		for _, m := range d.Members {
+			if _, live := p.live.users[m]; !live && !p.users[m] {
+				return errors.Errorf("group %q: user %q is not declared", d.Name, m)
+			}
+		}
+
+		var current []string
+		if g, ok := p.live.groups[d.Name]; ok {
+			items, err := p.r.groups.ReadMemberships(ctx, g.ID)
+			if err != nil {
+				return err
+			}
+			for _, i := range items {
+				current = append(current, p.live.describe(common.UserPrincipal(i.ID)))
+			}
+		}
+		var wanted []string
+		for _, m := range d.Members {
+			wanted = append(wanted, policydoc.Principal{Type: common.PrincipalTypeUser, Name: m}.String())
+		}
+
+		if details := diff(current, wanted); details != nil {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindMembership,
+				Name:    d.Name,
+				Details: details,
+				apply: func(ctx context.Context, a *applier) error {
+					ids := make([]int, 0, len(d.Members))
+					for _, m := range d.Members {
+						ids = append(ids, a.users[m])
+					}
+					_, err := a.r.groups.UpdateMemberships(ctx, a.groups[d.Name], ids)
+					return err
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planServicePrincipals(context.Context) error {
+	for _, d := range p.desired.ServicePrincipals {
+		key := spKey{d.Project, d.Name}
+		if _, live := p.live.projects[d.Project]; !live && !p.projects[d.Project] {
+			return errors.Errorf("service principal %q: project %q is not declared", key, d.Project)
+		}
+
+		cur, ok := p.live.servicePrincipals[key]
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindServicePrincipal,
+				Name:    key.String(),
+				Details: nonEmpty("description", d.Description),
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.servicePrincipals.Create(ctx, serviceprincipal.CreateParams{
+						ProjectID:   a.projects[d.Project],
+						Name:        d.Name,
+						Description: d.Description,
+					})
+					return err
+				},
+			})
+		} else if cur.Description != d.Description {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindServicePrincipal,
+				Name:    key.String(),
+				Details: []string{changed("description", cur.Description, d.Description)},
+				apply: func(ctx context.Context, a *applier) error {
+					_, err := a.r.servicePrincipals.Update(ctx, cur.ID, serviceprincipal.UpdateParams{
+						Name:        d.Name,
+						Description: v1.NewOptString(d.Description),
+					})
+					return err
+				},
+			})
+		}
+	}
+	return nil
+}
+
+func (p *planner) planPolicies(ctx context.Context) error {
+	if d := p.desired.Organization; d != nil {
+		cur, err := p.r.iamPolicy.ReadOrganizationPolicy(ctx)
+		if err != nil {
+			return err
+		}
+		if err := p.planIAMPolicy("organization", cur, d, func(*applier) iampolicy.Scope { return iampolicy.OrganizationScope() }); err != nil {
+			return err
+		}
+	}
+
+	if d := p.desired.IDPolicy; d != nil {
+		cur, err := p.r.idPolicy.ReadOrganizationIdPolicy(ctx)
+		if err != nil {
+			return err
+		}
+		var current []string
+		for _, b := range cur {
+			for _, i := range b.Principals {
+				current = append(current, b.Role.Value.ID.Value+" "+p.live.describe(i))
+			}
+		}
+		wanted, err := p.bindings(d, func(role string) (string, bool) {
+			i := slices.IndexFunc(p.live.idRoles, func(r v1.IdRole) bool { return r.ID == role || r.Name == role })
+			if i < 0 {
+				return "", false
+			}
+			return p.live.idRoles[i].ID, true
+		})
+		if err != nil {
+			return errors.Wrap(err, "id_policy")
+		}
+		if details := diff(current, wanted); details != nil {
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindIDPolicy,
+				Name:    "organization",
+				Details: details,
+				apply: func(ctx context.Context, a *applier) error {
+					b, err := a.resolver().IDPolicy(ctx, d)
+					if err != nil {
+						return err
+					}
+					_, err = a.r.idPolicy.UpdateOrganizationIdPolicy(ctx, b)
+					return err
+				},
+			})
+		}
+	}
+
+	for _, fp := range slices.SortedFunc(maps.Keys(p.folders), byDepth) {
+		d := p.folders[fp].Policy
+		if d == nil {
+			continue
+		}
+		var cur []v1.IamPolicy
+		if f, ok := p.live.folders[fp]; ok {
+			var err error
+			if cur, err = p.r.iamPolicy.ReadFolderPolicy(ctx, f.ID); err != nil {
+				return err
+			}
+		}
+		if err := p.planIAMPolicy("folder "+fp, cur, d, func(a *applier) iampolicy.Scope { return iampolicy.FolderScope(a.folders[fp]) }); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range p.desired.Projects {
+		if i.Policy == nil {
+			continue
+		}
+		var cur []v1.IamPolicy
+		if pr, ok := p.live.projects[i.Code]; ok {
+			var err error
+			if cur, err = p.r.iamPolicy.ReadProjectPolicy(ctx, pr.ID); err != nil {
+				return err
+			}
+		}
+		if err := p.planIAMPolicy("project "+i.Code, cur, i.Policy, func(a *applier) iampolicy.Scope { return iampolicy.ProjectScope(a.projects[i.Code]) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *planner) planIAMPolicy(name string, cur []v1.IamPolicy, d policydoc.Policy, scope func(*applier) iampolicy.Scope) error {
+	var current []string
+	for _, b := range cur {
+		for _, i := range b.Principals {
+			current = append(current, b.Role.Value.ID.Value+" "+p.live.describe(i))
+		}
+	}
+	wanted, err := p.bindings(d, func(role string) (string, bool) {
+		i := slices.IndexFunc(p.live.iamRoles, func(r v1.IamRole) bool { return r.ID == role || r.Name == role })
+		if i < 0 {
+			return "", false
+		}
+		return p.live.iamRoles[i].ID, true
+	})
+	if err != nil {
+		return errors.Wrap(err, name)
+	}
+
+	if details := diff(current, wanted); details != nil {
+		p.add(Change{
+			Action:  ActionUpdate,
+			Kind:    KindIAMPolicy,
+			Name:    name,
+			Details: details,
+			apply: func(ctx context.Context, a *applier) error {
+				b, err := a.resolver().IAMPolicy(ctx, d)
+				if err != nil {
+					return err
+				}
+				_, err = iampolicy.UpdatePolicy(ctx, a.r.iamPolicy, scope(a), b)
+				return err
+			},
+		})
+	}
+	return nil
+}
+
+// "<role ID> <principal>" for every pair in d, checking that both exist or
+// are going to
+func (p *planner) bindings(d policydoc.Policy, role func(string) (string, bool)) ([]string, error) {
+	var ret []string
+	for _, b := range d {
+		id, ok := role(b.Role)
+		if !ok {
+			return nil, errors.Errorf("role %q not found", b.Role)
+		}
+		for _, i := range b.Principals {
+			name, err := p.principal(i)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, id+" "+name)
+		}
+	}
+	return ret, nil
+}
+
+func (p *planner) principal(i policydoc.Principal) (string, error) {
+	if i.Name == "" {
+		ret := p.live.describe(v1.Principal{Type: v1.NewOptString(string(i.Type)), ID: v1.NewOptInt(i.ID)})
+		if ret != i.String() {
+			return ret, nil
+		}
+	} else {
+		var found bool
+		switch i.Type {
+		case common.PrincipalTypeUser:
+			_, found = p.live.users[i.Name]
+			found = found || p.users[i.Name]
+		case common.PrincipalTypeGroup:
+			_, found = p.live.groups[i.Name]
+			found = found || p.groups[i.Name]
+		case common.PrincipalTypeServicePrincipal:
+			_, found = p.live.servicePrincipals[spKey{i.Project, i.Name}]
+			found = found || p.servicePrincipals[spKey{i.Project, i.Name}]
+		}
+		if found {
+			return i.String(), nil
+		}
+	}
+	return "", errors.Errorf("principal %q not found", i)
+}
+
+func (p *planner) planDeletions() {
+	for _, k := range slices.SortedFunc(maps.Keys(p.live.servicePrincipals), func(a, b spKey) int { return strings.Compare(a.String(), b.String()) }) {
+		if !p.servicePrincipals[k] {
+			id := p.live.servicePrincipals[k].ID
+			p.add(Change{Action: ActionDelete, Kind: KindServicePrincipal, Name: k.String(), apply: func(ctx context.Context, a *applier) error {
+				return a.r.servicePrincipals.Delete(ctx, id)
+			}})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.live.groups)) {
+		if !p.groups[k] {
+			id := p.live.groups[k].ID
+			p.add(Change{Action: ActionDelete, Kind: KindGroup, Name: k, apply: func(ctx context.Context, a *applier) error {
+				return a.r.groups.Delete(ctx, id)
+			}})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.live.users)) {
+		if !p.users[k] {
+			id := p.live.users[k].ID
+			p.add(Change{Action: ActionDelete, Kind: KindUser, Name: k, apply: func(ctx context.Context, a *applier) error {
+				return a.r.users.Delete(ctx, id)
+			}})
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.live.projects)) {
+		if !p.projects[k] {
+			id := p.live.projects[k].ID
+			p.add(Change{Action: ActionDelete, Kind: KindProject, Name: k, apply: func(ctx context.Context, a *applier) error {
+				return a.r.projects.Delete(ctx, id)
+			}})
+		}
+	}
+	// children before parents
+	for _, k := range slices.Backward(slices.SortedFunc(maps.Keys(p.live.folders), byDepth)) {
+		if p.folders[k] == nil {
+			id := p.live.folders[k].ID
+			p.add(Change{Action: ActionDelete, Kind: KindFolder, Name: k, apply: func(ctx context.Context, a *applier) error {
+				return a.r.folders.Delete(ctx, id)
+			}})
+		}
+	}
+}
+
+// Apply carries out plan in order, stopping at the first failure.  Changes
+// before the failed one stay applied; plan again to see what is left.
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan) error {
+	a := applier{
+		r:        r,
+		users:    map[string]int{},
+		groups:   map[string]int{},
+		folders:  map[string]int{},
+		projects: map[string]int{},
+	}
+	if live := plan.live; live != nil {
+		for k, i := range live.users {
+			a.users[k] = i.ID
+		}
+		for k, i := range live.groups {
+			a.groups[k] = i.ID
+		}
+		for k, i := range live.folders {
+			a.folders[k] = i.ID
+		}
+		for k, i := range live.projects {
+			a.projects[k] = i.ID
+		}
+	}
+
+	for _, c := range plan.Changes {
+		if err := c.apply(ctx, &a); err != nil {
+			return common.NewError("Reconcile.Apply", errors.Wrap(err, c.String()))
+		}
+		if c.Action == ActionCreate {
+			a.res = nil
+		}
+	}
+	return nil
+}
+
+// IDs of what the plan refers to, including what Apply created so far
+type applier struct {
+	r        *Reconciler
+	users    map[string]int
+	groups   map[string]int
+	folders  map[string]int
+	projects map[string]int
+	res      *policydoc.Resolver
+}
+
+func (a *applier) folderID(path string) *int {
+	if path == "" {
+		return nil
+	}
+	return ptr(a.folders[path])
+}
+
+// policies are resolved by a Resolver that has seen everything created
+func (a *applier) resolver() *policydoc.Resolver {
+	if a.res == nil {
+		a.res = policydoc.NewResolver(a.r.client)
+	}
+	return a.res
+}
+
+// "+ x" for each of wanted missing from current, "- x" for the reverse
+func diff(current, wanted []string) []string {
+	var ret []string
+	for _, i := range slices.Compact(slices.Sorted(slices.Values(wanted))) {
+		if !slices.Contains(current, i) {
+			ret = append(ret, "+ "+i)
+		}
+	}
+	for _, i := range slices.Compact(slices.Sorted(slices.Values(current))) {
+		if !slices.Contains(wanted, i) {
+			ret = append(ret, "- "+i)
+		}
+	}
+	return ret
+}
+
+func nonEmpty(field, value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s: %q", field, value)}
+}
+
+func ptr[T any](v T) *T { return &v }
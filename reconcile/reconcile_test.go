@@ -0,0 +1,213 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/policydoc"
+	. "github.com/sacloud/iam-api-go/reconcile"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const desired = `
+users:
+  - {code: alice, name: Alice, password: password}
+  - {code: bob, name: Bob, password: password}
+groups:
+  - name: admins
+    description: Administrators
+    members: [alice]
+folders:
+  - path: production/web
+    description: web services
+    policy:
+      - role: folder-admin
+        principals: [group:admins]
+projects:
+  - code: web
+    name: Web
+    folder: production/web
+    policy:
+      - role: admin
+        principals: [user:bob, service-principal:web/deployer]
+service_principals:
+  - {project: web, name: deployer}
+organization:
+  - role: owner
+    principals: [user:alice]
+id_policy:
+  - role: identity-admin
+    principals: [group:admins]
+`
+
+func setup(t *testing.T, doc string) (*require.Assertions, *v1.Client, *State) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	var s State
+	assert.NoError(yaml.Unmarshal([]byte(doc), &s))
+	return assert, client, &s
+}
+
+func TestPlanApply(t *testing.T) {
+	assert, client, state := setup(t, desired)
+	r := NewReconciler(client)
+
+	plan, err := r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	var order []string
+	for _, c := range plan.Changes {
+		order = append(order, c.String())
+	}
+	assert.Equal([]string{
+		`create folder "production"`,
+		`create folder "production/web"`,
+		`create project "web"`,
+		`create user "alice"`,
+		`create user "bob"`,
+		`create group "admins"`,
+		`update membership "admins"`,
+		`create service-principal "web/deployer"`,
+		`update iam-policy "organization"`,
+		`update id-policy "organization"`,
+		`update iam-policy "folder production/web"`,
+		`update iam-policy "project web"`,
+	}, order)
+	assert.Contains(plan.String(), "Plan: 7 to create, 5 to update, 0 to move, 0 to delete.")
+	assert.Contains(plan.String(), "    + admin service-principal:web/deployer\n")
+
+	assert.NoError(r.Apply(t.Context(), plan))
+
+	again, err := r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.True(again.Empty(), again.String())
+
+	exported, err := policydoc.NewResolver(client).Export(t.Context())
+	assert.NoError(err)
+	assert.Equal(policydoc.Policy{{Role: "owner", Principals: []policydoc.Principal{{Type: iam.PrincipalTypeUser, Name: "alice"}}}}, exported.Organization)
+	assert.Len(exported.Projects["web"][0].Principals, 2)
+}
+
+func TestPlan_Update(t *testing.T) {
+	assert, client, state := setup(t, desired)
+	r := NewReconciler(client)
+	plan, err := r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.NoError(r.Apply(t.Context(), plan))
+
+	state.Groups[0].Members = []string{"bob"}
+	state.Projects[0].Name = "Website"
+	state.Projects[0].Folder = "production"
+	state.Organization = policydoc.Policy{}
+
+	plan, err = r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.Equal(`~ project "web"
+    name: "Web" => "Website"
+> project "web"
+    folder: "production/web" => "production"
+~ membership "admins"
+    + user:bob
+    - user:alice
+~ iam-policy "organization"
+    - owner user:alice
+
+Plan: 0 to create, 3 to update, 1 to move, 0 to delete.
+`, plan.String())
+
+	assert.NoError(r.Apply(t.Context(), plan))
+	again, err := r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.True(again.Empty(), again.String())
+}
+
+func TestPlan_HiddenParent(t *testing.T) {
+	assert := require.New(t)
+	client, sv := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	folders := iam.NewFolderOp(client)
+	parent, err := folders.Create(ctx, folder.CreateParams{Name: "production"})
+	assert.NoError(err)
+	_, err = folders.Create(ctx, folder.CreateParams{Name: "web", ParentID: &parent.ID})
+	assert.NoError(err)
+	sv.HideFolder(parent.ID)
+
+	var state State
+	assert.NoError(yaml.Unmarshal([]byte("folders: [{path: web}]"), &state))
+	plan, err := NewReconciler(client).Plan(ctx, &state, Options{})
+	assert.NoError(err)
+	assert.True(plan.Empty(), plan.String())
+}
+
+func TestPlan_Prune(t *testing.T) {
+	assert, client, state := setup(t, desired)
+	r := NewReconciler(client)
+	plan, err := r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.NoError(r.Apply(t.Context(), plan))
+
+	_, err = iam.NewUserOp(client).Create(t.Context(), user.CreateParams{Code: "carol", Name: "Carol", Password: "password"})
+	assert.NoError(err)
+	state.ServicePrincipals = nil
+	state.Projects[0].Policy = nil
+	state.Folders = nil
+	state.Projects[0].Folder = ""
+
+	plan, err = r.Plan(t.Context(), state, Options{})
+	assert.NoError(err)
+	assert.Zero(plan.Count(ActionDelete))
+
+	plan, err = r.Plan(t.Context(), state, Options{Prune: true})
+	assert.NoError(err)
+	assert.True(strings.HasSuffix(plan.String(), `- service-principal "web/deployer"
+- user "carol"
+- folder "production/web"
+- folder "production"
+
+Plan: 0 to create, 0 to update, 1 to move, 4 to delete.
+`), plan.String())
+
+	assert.NoError(r.Apply(t.Context(), plan))
+	again, err := r.Plan(t.Context(), state, Options{Prune: true})
+	assert.NoError(err)
+	assert.True(again.Empty(), again.String())
+}
+
+func TestPlan_Invalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"duplicate user":  "users: [{code: a, name: a, password: p}, {code: a, name: b, password: p}]",
+		"no password":     "users: [{code: a, name: a}]",
+		"unknown member":  "groups: [{name: g, members: [nobody]}]",
+		"unknown folder":  "projects: [{code: p, name: p, folder: nowhere}]",
+		"unknown project": "service_principals: [{project: nowhere, name: sp}]",
+		"unknown role":    "organization: [{role: janitor, principals: []}]",
+		"unknown user":    "organization: [{role: owner, principals: ['user:nobody']}]",
+		"unknown id":      "organization: [{role: owner, principals: ['user:#1']}]",
+		"bad path":        "folders: [{path: a//b}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert, client, state := setup(t, doc)
+			_, err := NewReconciler(client).Plan(t.Context(), state, Options{})
+			assert.Error(err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reconcile brings an organization to a declared state, Terraform
+// style: Plan compares a State against what the API reports and lists the
+// changes needed, Apply carries them out in dependency order.
+//
+// Resources are identified by name: users by code, groups by name, folders
+// by path, projects by code and service principals by project code and
+// name.  Renaming a folder therefore reads as deleting one and creating
+// another.  Nothing is deleted unless Options.Prune is set.
+package reconcile
+
+import "github.com/sacloud/iam-api-go/policydoc"
+
+// State is the desired configuration of an organization.  Policies use the
+// policydoc notation; a nil Policy is left as it is, an empty one is
+// cleared.
+type State struct {
+	Users             []User             `json:"users,omitempty" yaml:"users,omitempty"`
+	Groups            []Group            `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Folders           []Folder           `json:"folders,omitempty" yaml:"folders,omitempty"`
+	Projects          []Project          `json:"projects,omitempty" yaml:"projects,omitempty"`
+	ServicePrincipals []ServicePrincipal `json:"service_principals,omitempty" yaml:"service_principals,omitempty"`
+	Organization      policydoc.Policy   `json:"organization,omitzero" yaml:"organization,omitempty"`
+	IDPolicy          policydoc.Policy   `json:"id_policy,omitzero" yaml:"id_policy,omitempty"`
+}
+
+// User is identified by Code.  Email and Password are only used to create
+// the user; they are never compared with, nor pushed to, an existing one.
+type User struct {
+	Code        string `json:"code" yaml:"code"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Email       string `json:"email,omitempty" yaml:"email,omitempty"`
+	Password    string `json:"password,omitempty" yaml:"password,omitempty"`
+}
+
+// Group is identified by Name.  Members are user codes; nil leaves the
+// memberships alone.
+type Group struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Members     []string `json:"members,omitzero" yaml:"members,omitempty"`
+}
+
+// Folder is identified by its path of names from the top, separated by "/".
+// Missing ancestors are created as well.
+type Folder struct {
+	Path        string           `json:"path" yaml:"path"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Policy      policydoc.Policy `json:"policy,omitzero" yaml:"policy,omitempty"`
+}
+
+// Project is identified by Code.  Folder is the path of its parent folder,
+// empty for the top of the hierarchy.
+type Project struct {
+	Code        string           `json:"code" yaml:"code"`
+	Name        string           `json:"name" yaml:"name"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Folder      string           `json:"folder,omitempty" yaml:"folder,omitempty"`
+	Policy      policydoc.Policy `json:"policy,omitzero" yaml:"policy,omitempty"`
+}
+
+// ServicePrincipal is identified by the code of its project and its name.
+type ServicePrincipal struct {
+	Project     string `json:"project" yaml:"project"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Options tune Plan.
+type Options struct {
+	// Prune deletes users, groups, folders, projects and service principals
+	// that State does not mention.
+	Prune bool
+}
@@ -0,0 +1,202 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iam
+
+import (
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ht "github.com/ogen-go/ogen/http"
+)
+
+// RetryPolicy configures automatic retries of 429 Too Many Requests and 503
+// Service Unavailable responses.
+//
+// A 429 means the request was rejected before being processed, so it is
+// retried regardless of the HTTP method.  A 503 carries no such guarantee and
+// is only retried when Idempotent reports the request as safe to resend.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the wait before the second attempt.  It doubles on every
+	// subsequent attempt, with up to half of it randomised away as jitter.
+	BaseDelay time.Duration
+
+	// MaxDelay caps any single wait.  A Retry-After header asking for more
+	// than this ends the retries and hands the response back to the caller.
+	MaxDelay time.Duration
+
+	// Idempotent decides whether a request may be resent after a 503.
+	// Defaults to IsIdempotentRequest.
+	Idempotent func(*http.Request) bool
+}
+
+// DefaultRetryPolicy is a reasonable starting point for bulk jobs.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// Option customises the client built by NewClient / NewClientWithAPIRootURL.
+type Option func(*options)
+
+type options struct {
+	retry *RetryPolicy
+}
+
+// WithRetryPolicy enables retries of 429 and 503 responses.  It replaces the
+// generic retry logic of saclient for this client, which would otherwise
+// resend every request (including non-idempotent POSTs) and hide the final
+// status code from the caller.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(o *options) { o.retry = &p }
+}
+
+// POST endpoints that only flip a state, hence safe to resend.
+var idempotentActions = []string{
+	"/enable",
+	"/disable",
+	"/assign",
+	"/unassign",
+	"/deactivate-otp",
+	"/clear-trusted-devices",
+	"/unregister-email",
+	"/move-folders",
+	"/move-projects",
+	"/enable-service-policy",
+	"/disable-service-policy",
+}
+
+// IsIdempotentRequest reports whether resending req cannot create duplicate
+// resources: every GET, HEAD, OPTIONS, PUT and DELETE, plus those POST
+// endpoints that merely switch something on or off.  Creations such as
+// CompatUsersPost are not idempotent.
+func IsIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		for _, i := range idempotentActions {
+			if strings.HasSuffix(req.URL.Path, i) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func (p *RetryPolicy) retryable(req *http.Request, res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		if p.Idempotent != nil {
+			return p.Idempotent(req)
+		} else {
+			return IsIdempotentRequest(req)
+		}
+	default:
+		return false
+	}
+}
+
+// delay computes the wait before attempt n+1; false means give up.
+func (p *RetryPolicy) delay(n int, res *http.Response) (time.Duration, bool) {
+	if d, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+		return d, p.MaxDelay <= 0 || d <= p.MaxDelay
+	}
+
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if half := int64(d / 2); half > 0 {
+		d = time.Duration(half + rand.Int64N(half+1)) // #nosec G404 -- jitter only
+	}
+	return d, true
+}
+
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	} else if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	} else if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	} else {
+		return 0, false
+	}
+}
+
+type retryDoer struct {
+	next   ht.Client
+	policy RetryPolicy
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	for n := 1; ; n++ {
+		res, err := d.next.Do(req)
+
+		if err != nil || n >= d.policy.MaxAttempts || !d.policy.retryable(req, res) {
+			return res, err
+		}
+
+		wait, ok := d.policy.delay(n, res)
+		if !ok {
+			return res, nil
+		}
+
+		retry, err := rewind(req)
+		if err != nil {
+			return res, nil
+		}
+
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+			req = retry
+		}
+	}
+}
+
+// rewind prepares req to be sent once more.
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	} else if req.GetBody == nil {
+		return nil, NewError("request body cannot be rewound", nil)
+	} else if body, err := req.GetBody(); err != nil {
+		return nil, err
+	} else {
+		ret := req.Clone(req.Context())
+		ret.Body = body
+		return ret, nil
+	}
+}
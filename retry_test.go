@@ -0,0 +1,214 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iam_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/group"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/saclient-go"
+	"github.com/stretchr/testify/require"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    10 * time.Millisecond,
+}
+
+// scriptedServer answers with the given statuses in order, then succeeds.
+type scriptedServer struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	bodies   []string
+	server   *httptest.Server
+}
+
+func newScriptedServer(t *testing.T, statuses ...int) *scriptedServer {
+	s := &scriptedServer{statuses: statuses, header: http.Header{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+func (s *scriptedServer) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	st := http.StatusOK
+	if r.Method == http.MethodPost {
+		st = http.StatusCreated
+	}
+	if n := len(s.bodies); n < len(s.statuses) {
+		st = s.statuses[n]
+	}
+	s.bodies = append(s.bodies, string(body))
+	s.mu.Unlock()
+
+	var res any
+	switch st {
+	case http.StatusOK, http.StatusCreated:
+		var g v1.Group
+		g.SetFake()
+		res = &g
+	default:
+		var e v1.Http503ServiceUnavailable
+		e.SetFake()
+		e.SetStatus(st)
+		res = &e
+	}
+	j, _ := json.Marshal(res)
+
+	for k, v := range s.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(st)
+	_, _ = w.Write(j)
+}
+
+func (s *scriptedServer) Bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bodies
+}
+
+func (s *scriptedServer) client(t *testing.T, p RetryPolicy) group.GroupAPI {
+	var theClient saclient.Client
+	sa, err := theClient.DupWith(saclient.WithTestServer(s.server))
+	require.NoError(t, err)
+	c, err := NewClientWithAPIRootURL(sa, s.server.URL, WithRetryPolicy(p))
+	require.NoError(t, err)
+	require.NoError(t, sa.Populate())
+	return group.NewGroupOp(c)
+}
+
+func TestRetry_RateLimited(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusTooManyRequests, http.StatusTooManyRequests)
+
+	actual, err := s.client(t, testRetryPolicy).Read(t.Context(), 1)
+	assert.NoError(err)
+	assert.NotNil(actual)
+	assert.Len(s.Bodies(), 3)
+}
+
+func TestRetry_GiveUp(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	actual, err := s.client(t, testRetryPolicy).Read(t.Context(), 1)
+	assert.Error(err)
+	assert.Nil(actual)
+	assert.True(IsUnavailable(err))
+	assert.Len(s.Bodies(), 3)
+}
+
+func TestRetry_NonIdempotent(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusServiceUnavailable)
+
+	actual, err := s.client(t, testRetryPolicy).Create(t.Context(), "name", "description")
+	assert.Error(err)
+	assert.Nil(actual)
+	assert.True(IsUnavailable(err))
+	assert.Len(s.Bodies(), 1)
+}
+
+func TestRetry_NonIdempotentRateLimited(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusTooManyRequests)
+
+	actual, err := s.client(t, testRetryPolicy).Create(t.Context(), "name", "description")
+	assert.NoError(err)
+	assert.NotNil(actual)
+
+	bodies := s.Bodies()
+	assert.Len(bodies, 2)
+	assert.NotEmpty(bodies[0])
+	assert.Equal(bodies[0], bodies[1])
+}
+
+func TestRetry_RetryAfterTooLong(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusTooManyRequests)
+	s.header.Set("Retry-After", "3600")
+
+	_, err := s.client(t, testRetryPolicy).Read(t.Context(), 1)
+	assert.True(IsRateLimited(err))
+	assert.Len(s.Bodies(), 1)
+}
+
+func TestRetry_RetryAfter(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusTooManyRequests)
+	s.header.Set("Retry-After", "1")
+	p := testRetryPolicy
+	p.MaxDelay = 2 * time.Second
+
+	start := time.Now()
+	_, err := s.client(t, p).Read(t.Context(), 1)
+	assert.NoError(err)
+	assert.GreaterOrEqual(time.Since(start), time.Second)
+	assert.Len(s.Bodies(), 2)
+}
+
+func TestRetry_Cancel(t *testing.T) {
+	assert := require.New(t)
+	s := newScriptedServer(t, http.StatusTooManyRequests)
+	p := testRetryPolicy
+	p.BaseDelay = time.Minute
+	p.MaxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.client(t, p).Read(ctx, 1)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Len(s.Bodies(), 1)
+}
+
+func TestIsIdempotentRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodGet, "/groups", true},
+		{http.MethodPut, "/groups/1", true},
+		{http.MethodDelete, "/groups/1", true},
+		{http.MethodPost, "/groups", false},
+		{http.MethodPost, "/compat/users", false},
+		{http.MethodPost, "/service-principals/1/upload-key", false},
+		{http.MethodPost, "/service-principals/1/keys/x/disable", true},
+		{http.MethodPost, "/move-projects", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			require.New(t).Equal(tt.want, IsIdempotentRequest(req))
+		})
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionRemove Action = "remove"
+	ActionChange Action = "change"
+)
+
+func (a Action) symbol() string {
+	switch a {
+	case ActionAdd:
+		return "+"
+	case ActionRemove:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// Section is the part of the configuration a change is in.  Diff lists
+// changes in the order of these constants.
+type Section string
+
+const (
+	SectionOrganization     Section = "organization"
+	SectionPasswordPolicy   Section = "password-policy"
+	SectionAuthConditions   Section = "auth-conditions"
+	SectionServicePolicy    Section = "service-policy"
+	SectionFolder           Section = "folder"
+	SectionProject          Section = "project"
+	SectionUser             Section = "user"
+	SectionGroup            Section = "group"
+	SectionMembership       Section = "membership"
+	SectionServicePrincipal Section = "service-principal"
+	SectionKey              Section = "key"
+	SectionBinding          Section = "binding"
+	SectionSSOProfile       Section = "sso-profile"
+	SectionSCIM             Section = "scim"
+)
+
+// Change is one difference between two snapshots.
+type Change struct {
+	Action  Action
+	Section Section
+	Name    string   // what changed, in words; empty for the settings sections
+	Details []string // for ActionChange, the fields: `name: "a" => "b"`
+}
+
+type Diff struct {
+	Changes []Change
+
+	err error // the first entity that could not be compared
+}
+
+func (d *Diff) Empty() bool { return len(d.Changes) == 0 }
+
+// String renders the diff for humans, a line per change marked "+" for
+// added, "-" for removed and "~" for changed, followed by the fields that
+// changed.
+func (d *Diff) String() string {
+	if d.Empty() {
+		return "No changes.\n"
+	}
+	var b strings.Builder
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "%s %s", c.Action.symbol(), c.Section)
+		if c.Name != "" {
+			fmt.Fprintf(&b, " %s", c.Name)
+		}
+		b.WriteString("\n")
+		for _, i := range c.Details {
+			fmt.Fprintf(&b, "    %s\n", i)
+		}
+	}
+	return b.String()
+}
+
+// Compare lists what changed from before to after.  Resources are matched
+// by ID, so one deleted and recreated under the same name shows up as
+// removed and added.  Timestamps are not compared.  It fails if an entity
+// of either snapshot cannot be encoded as JSON.
+func Compare(before, after *Snapshot) (*Diff, error) {
+	var d Diff
+	d.fields(SectionOrganization, "", &before.Organization, &after.Organization)
+	d.fields(SectionPasswordPolicy, "", &before.PasswordPolicy, &after.PasswordPolicy)
+	d.fields(SectionAuthConditions, "", &before.AuthConditions, &after.AuthConditions)
+
+	if before.ServicePolicy.Enabled != after.ServicePolicy.Enabled {
+		d.add(Change{Action: ActionChange, Section: SectionServicePolicy, Name: servicePolicyStatus,
+			Details: []string{fmt.Sprintf("enabled: %t => %t", before.ServicePolicy.Enabled, after.ServicePolicy.Enabled)}})
+	}
+	diffEntities(&d, SectionServicePolicy, before.ServicePolicy.Rules, after.ServicePolicy.Rules,
+		func(r *v1.RuleResponse) string { return r.Code.Value },
+		func(_ *Snapshot, r *v1.RuleResponse) string { return "rule " + r.Code.Value }, before, after)
+
+	diffEntities(&d, SectionFolder, before.Folders, after.Folders,
+		func(f *Folder) string { return strconv.Itoa(f.Folder.ID) },
+		func(s *Snapshot, f *Folder) string {
+			return fmt.Sprintf("%s (#%d)", s.folderPath(f.Folder.ID), f.Folder.ID)
+		},
+		before, after, func(f *Folder) any { return &f.Folder })
+	diffEntities(&d, SectionProject, before.Projects, after.Projects,
+		func(p *Project) string { return strconv.Itoa(p.Project.ID) },
+		func(_ *Snapshot, p *Project) string { return fmt.Sprintf("%s (#%d)", p.Project.Code, p.Project.ID) },
+		before, after, func(p *Project) any { return &p.Project })
+	diffEntities(&d, SectionUser, before.Users, after.Users,
+		func(u *v1.User) string { return strconv.Itoa(u.ID) },
+		func(_ *Snapshot, u *v1.User) string { return fmt.Sprintf("%s (#%d)", u.Code, u.ID) }, before, after)
+	diffEntities(&d, SectionGroup, before.Groups, after.Groups,
+		func(g *Group) string { return strconv.Itoa(g.Group.ID) },
+		func(_ *Snapshot, g *Group) string { return fmt.Sprintf("%s (#%d)", g.Group.Name, g.Group.ID) },
+		before, after, func(g *Group) any { return &g.Group })
+	d.memberships(before, after)
+	diffEntities(&d, SectionServicePrincipal, before.ServicePrincipals, after.ServicePrincipals,
+		func(sp *ServicePrincipal) string { return strconv.Itoa(sp.ServicePrincipal.ID) },
+		func(s *Snapshot, sp *ServicePrincipal) string {
+			return s.principal(common.ServicePrincipalPrincipal(sp.ServicePrincipal.ID))
+		},
+		before, after, func(sp *ServicePrincipal) any { return &sp.ServicePrincipal })
+	d.keys(before, after)
+	d.bindings(before, after)
+	diffEntities(&d, SectionSSOProfile, before.SSOProfiles, after.SSOProfiles,
+		func(p *v1.SSOProfile) string { return strconv.Itoa(p.ID) },
+		func(_ *Snapshot, p *v1.SSOProfile) string { return fmt.Sprintf("%s (#%d)", p.Name, p.ID) }, before, after)
+	diffEntities(&d, SectionSCIM, before.SCIMConfigurations, after.SCIMConfigurations,
+		func(c *v1.ScimConfigurationBase) string { return c.ID.String() },
+		func(_ *Snapshot, c *v1.ScimConfigurationBase) string { return fmt.Sprintf("%s (%s)", c.Name, c.ID) }, before, after)
+	if d.err != nil {
+		return nil, common.NewError("Snapshot.Compare", d.err)
+	}
+	return &d, nil
+}
+
+// Drift compares before with the organization as it is now.
+func (e *Exporter) Drift(ctx context.Context, before *Snapshot) (*Diff, error) {
+	now, err := e.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Compare(before, now)
+}
+
+// the name of the change to whether the service policy is enabled
+const servicePolicyStatus = "status"
+
+func (d *Diff) add(c Change) { d.Changes = append(d.Changes, c) }
+
+// a change listing the fields that differ, if any
+func (d *Diff) fields(section Section, name string, before, after any) {
+	o, err := flatten(before)
+	if err != nil {
+		d.fail(err, section, name)
+		return
+	}
+	n, err := flatten(after)
+	if err != nil {
+		d.fail(err, section, name)
+		return
+	}
+
+	var details []string
+	for _, k := range slices.Sorted(maps.Keys(merged(o, n))) {
+		if o[k] != n[k] {
+			details = append(details, fmt.Sprintf("%s: %s => %s", k, cmp.Or(o[k], "(none)"), cmp.Or(n[k], "(none)")))
+		}
+	}
+	if len(details) > 0 {
+		d.add(Change{Action: ActionChange, Section: section, Name: name, Details: details})
+	}
+}
+
+// keeps the first error only
+func (d *Diff) fail(err error, section Section, name string) {
+	if d.err == nil {
+		d.err = errors.Wrap(err, strings.TrimSpace(fmt.Sprintf("%s %s", section, name)))
+	}
+}
+
+// diffEntities matches before and after by id.  fields, if given, picks the
+// part of an entity whose fields are compared; the whole entity otherwise.
+func diffEntities[T any](
+	d *Diff,
+	section Section,
+	before, after []T,
+	id func(*T) string,
+	name func(*Snapshot, *T) string,
+	from, to *Snapshot,
+	fields ...func(*T) any,
+) {
+	pick := func(t *T) any { return t }
+	if len(fields) > 0 {
+		pick = fields[0]
+	}
+	byID := func(s []T) map[string]*T {
+		ret := make(map[string]*T, len(s))
+		for i := range s {
+			ret[id(&s[i])] = &s[i]
+		}
+		return ret
+	}
+	was, is := byID(before), byID(after)
+
+	var changes []Change
+	for k, o := range was {
+		if _, ok := is[k]; !ok {
+			changes = append(changes, Change{Action: ActionRemove, Section: section, Name: name(from, o)})
+		}
+	}
+	for k, n := range is {
+		o, ok := was[k]
+		if !ok {
+			changes = append(changes, Change{Action: ActionAdd, Section: section, Name: name(to, n)})
+			continue
+		}
+		var sub Diff
+		sub.fields(section, name(to, n), pick(o), pick(n))
+		changes = append(changes, sub.Changes...)
+		if sub.err != nil && d.err == nil {
+			d.err = sub.err
+		}
+	}
+	d.Changes = append(d.Changes, sorted(changes)...)
+}
+
+func (d *Diff) memberships(before, after *Snapshot) {
+	var changes []Change
+	for _, g := range after.Groups {
+		i := slices.IndexFunc(before.Groups, func(o Group) bool { return o.Group.ID == g.Group.ID })
+		if i < 0 {
+			continue // the group is new, and so are its members
+		}
+		for _, id := range g.Members {
+			if !slices.Contains(before.Groups[i].Members, id) {
+				changes = append(changes, Change{Action: ActionAdd, Section: SectionMembership,
+					Name: fmt.Sprintf("%s in group %s", after.principal(common.UserPrincipal(id)), g.Group.Name)})
+			}
+		}
+		for _, id := range before.Groups[i].Members {
+			if !slices.Contains(g.Members, id) {
+				changes = append(changes, Change{Action: ActionRemove, Section: SectionMembership,
+					Name: fmt.Sprintf("%s in group %s", before.principal(common.UserPrincipal(id)), g.Group.Name)})
+			}
+		}
+	}
+	d.Changes = append(d.Changes, sorted(changes)...)
+}
+
+func (d *Diff) keys(before, after *Snapshot) {
+	type key struct {
+		sp *v1.ServicePrincipal
+		v1.ServicePrincipalKey
+	}
+	all := func(s *Snapshot) []key {
+		var ret []key
+		for i := range s.ServicePrincipals {
+			for _, k := range s.ServicePrincipals[i].Keys {
+				ret = append(ret, key{&s.ServicePrincipals[i].ServicePrincipal, k})
+			}
+		}
+		return ret
+	}
+	diffEntities(d, SectionKey, all(before), all(after),
+		func(k *key) string { return k.ID.String() },
+		func(s *Snapshot, k *key) string {
+			return fmt.Sprintf("%s of %s", k.Kid, s.principal(common.ServicePrincipalPrincipal(k.sp.ID)))
+		},
+		before, after, func(k *key) any { return &k.ServicePrincipalKey })
+}
+
+func (d *Diff) bindings(before, after *Snapshot) {
+	// scope => role => principal, scopes keyed by ID so that renaming or
+	// moving a folder or project changes no binding
+	type grants map[string]map[string]map[v1.Principal]bool
+	collect := func(s *Snapshot) (grants, map[string]string) {
+		ret, names := grants{}, map[string]string{}
+		grant := func(scope, name, role string, principals []v1.Principal) {
+			names[scope] = name
+			if ret[scope] == nil {
+				ret[scope] = map[string]map[v1.Principal]bool{}
+			}
+			if ret[scope][role] == nil {
+				ret[scope][role] = map[v1.Principal]bool{}
+			}
+			for _, p := range principals {
+				ret[scope][role][p] = true
+			}
+		}
+		for _, b := range s.IAMPolicy {
+			grant("organization", "organization", b.Role.Value.ID.Value, b.Principals)
+		}
+		for _, b := range s.IDPolicy {
+			grant("id-policy", "id-policy", b.Role.Value.ID.Value, b.Principals)
+		}
+		for _, f := range s.Folders {
+			for _, b := range f.IAMPolicy {
+				grant(fmt.Sprintf("folder #%d", f.Folder.ID), "folder "+s.folderPath(f.Folder.ID), b.Role.Value.ID.Value, b.Principals)
+			}
+		}
+		for _, p := range s.Projects {
+			for _, b := range p.IAMPolicy {
+				grant(fmt.Sprintf("project #%d", p.Project.ID), "project "+p.Project.Code, b.Role.Value.ID.Value, b.Principals)
+			}
+		}
+		return ret, names
+	}
+
+	var changes []Change
+	compare := func(action Action, these, others grants, names map[string]string, s *Snapshot) {
+		for scope, roles := range these {
+			for role, principals := range roles {
+				for p := range principals {
+					if !others[scope][role][p] {
+						changes = append(changes, Change{Action: action, Section: SectionBinding,
+							Name: fmt.Sprintf("%s: %s to %s", names[scope], role, s.principal(p))})
+					}
+				}
+			}
+		}
+	}
+	o, oldNames := collect(before)
+	n, newNames := collect(after)
+	compare(ActionRemove, o, n, oldNames, before)
+	compare(ActionAdd, n, o, newNames, after)
+	d.Changes = append(d.Changes, sorted(changes)...)
+}
+
+func sorted(c []Change) []Change {
+	slices.SortFunc(c, func(a, b Change) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(string(a.Action), string(b.Action)))
+	})
+	return c
+}
+
+// "/a/b" for a folder, following parents within s
+func (s *Snapshot) folderPath(id int) string {
+	var names []string
+	for len(names) <= len(s.Folders) {
+		i := slices.IndexFunc(s.Folders, func(f Folder) bool { return f.Folder.ID == id })
+		if i < 0 {
+			break
+		}
+		names = append(names, s.Folders[i].Folder.Name)
+		if s.Folders[i].Folder.ParentID.Null {
+			break
+		}
+		id = s.Folders[i].Folder.ParentID.Value
+	}
+	slices.Reverse(names)
+	return "/" + strings.Join(names, "/")
+}
+
+// a principal by name, such as "user alice" or "service-principal
+// web/deployer", or by ID if s does not know it
+func (s *Snapshot) principal(p v1.Principal) string {
+	t, id := common.PrincipalTypeOf(p), p.ID.Value
+	switch t {
+	case common.PrincipalTypeUser:
+		if i := slices.IndexFunc(s.Users, func(u v1.User) bool { return u.ID == id }); i >= 0 {
+			return fmt.Sprintf("%s %s", t, s.Users[i].Code)
+		}
+	case common.PrincipalTypeGroup:
+		if i := slices.IndexFunc(s.Groups, func(g Group) bool { return g.Group.ID == id }); i >= 0 {
+			return fmt.Sprintf("%s %s", t, s.Groups[i].Group.Name)
+		}
+	case common.PrincipalTypeServicePrincipal:
+		if i := slices.IndexFunc(s.ServicePrincipals, func(sp ServicePrincipal) bool { return sp.ServicePrincipal.ID == id }); i >= 0 {
+			sp := s.ServicePrincipals[i].ServicePrincipal
+			project := fmt.Sprintf("#%d", sp.ProjectID)
+			if j := slices.IndexFunc(s.Projects, func(p Project) bool { return p.Project.ID == sp.ProjectID }); j >= 0 {
+				project = s.Projects[j].Project.Code
+			}
+			return fmt.Sprintf("%s %s/%s", t, project, sp.Name)
+		}
+	}
+	return fmt.Sprintf("%s #%d", t, id)
+}
+
+// v as a flat map of dotted JSON paths to JSON values, timestamps left out
+func flatten(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+
+	ret := map[string]string{}
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		if m, ok := v.(map[string]any); ok {
+			for k, i := range m {
+				if k == "created_at" || k == "updated_at" {
+					continue
+				}
+				walk(strings.TrimPrefix(prefix+"."+k, "."), i)
+			}
+			return
+		}
+		b, _ := json.Marshal(v)
+		ret[prefix] = string(b)
+	}
+	walk("", tree)
+	return ret, nil
+}
+
+func merged(a, b map[string]string) map[string]string {
+	ret := maps.Clone(a)
+	maps.Copy(ret, b)
+	return ret
+}
@@ -0,0 +1,109 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot_test
+
+import (
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/snapshot"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func base() *Snapshot {
+	return &Snapshot{
+		Version:        Version,
+		PasswordPolicy: v1.PasswordPolicy{MinLength: 8},
+		Folders:        []Folder{{Folder: v1.Folder{ID: 1, Name: "prod", ParentID: v1.NilInt{Null: true}}}},
+		Users: []v1.User{
+			{ID: 10, Code: "alice", Name: "Alice"},
+			{ID: 11, Code: "bob", Name: "Bob"},
+		},
+		Groups: []Group{{Group: v1.Group{ID: 20, Name: "admins"}, Members: []int{10}}},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	assert := require.New(t)
+	before, after := base(), base()
+
+	d, err := Compare(before, after)
+	assert.NoError(err)
+	assert.True(d.Empty())
+	assert.Equal("No changes.\n", d.String())
+
+	after.PasswordPolicy.MinLength = 12
+	after.Users = []v1.User{{ID: 10, Code: "alice", Name: "Alice Smith", UpdatedAt: "later"}, {ID: 12, Code: "carol"}}
+	after.Groups[0].Members = []int{12}
+	after.Folders[0].IAMPolicy = []v1.IamPolicy{iam.IAMBinding("folder-admin", iam.GroupPrincipal(20))}
+	after.IAMPolicy = []v1.IamPolicy{iam.IAMBinding("owner", iam.UserPrincipal(99))}
+
+	d, err = Compare(before, after)
+	assert.NoError(err)
+	assert.Equal(`~ password-policy
+    min_length: 8 => 12
+~ user alice (#10)
+    name: "Alice" => "Alice Smith"
+- user bob (#11)
++ user carol (#12)
+- membership user alice in group admins
++ membership user carol in group admins
++ binding folder /prod: folder-admin to group admins
++ binding organization: owner to user #99
+`, d.String())
+	assert.Equal(ActionChange, d.Changes[0].Action)
+	assert.Equal(SectionPasswordPolicy, d.Changes[0].Section)
+}
+
+func TestCompare_Rename(t *testing.T) {
+	assert := require.New(t)
+	before, after := base(), base()
+	before.Folders[0].IAMPolicy = []v1.IamPolicy{iam.IAMBinding("folder-admin", iam.GroupPrincipal(20))}
+	after.Folders[0].IAMPolicy = []v1.IamPolicy{iam.IAMBinding("folder-admin", iam.GroupPrincipal(20), iam.UserPrincipal(11))}
+	after.Folders[0].Folder.Name = "production"
+
+	d, err := Compare(before, after)
+	assert.NoError(err)
+	assert.Equal(`~ folder /production (#1)
+    name: "prod" => "production"
++ binding folder /production: folder-admin to user bob
+`, d.String())
+}
+
+func TestDrift(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	populate(t, client)
+
+	e := NewExporter(client)
+	before, err := e.Export(ctx)
+	assert.NoError(err)
+
+	d, err := e.Drift(ctx, before)
+	assert.NoError(err)
+	assert.True(d.Empty(), d.String())
+
+	_, err = iam.NewUserOp(client).Create(ctx, user.CreateParams{Code: "mallory", Name: "Mallory", Password: "hunter2hunter2"})
+	assert.NoError(err)
+	d, err = e.Drift(ctx, before)
+	assert.NoError(err)
+	assert.Len(d.Changes, 1)
+	assert.Equal(Change{Action: ActionAdd, Section: SectionUser, Name: d.Changes[0].Name}, d.Changes[0])
+	assert.Contains(d.Changes[0].Name, "mallory")
+}
@@ -0,0 +1,348 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-faster/errors"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+	"github.com/sacloud/iam-api-go/policydoc"
+	"github.com/sacloud/iam-api-go/reconcile"
+)
+
+// Part is a portion of a snapshot that can be restored on its own.
+type Part string
+
+const (
+	PartHierarchy     Part = "hierarchy"      // folders, projects and their service principals
+	PartGroups        Part = "groups"         // groups and their members
+	PartPolicies      Part = "policies"       // IAM policies of every scope and the ID policy
+	PartAuth          Part = "auth"           // password policy and auth conditions
+	PartServicePolicy Part = "service-policy" // status and rules
+)
+
+func (p Part) AllValues() []Part {
+	return []Part{PartHierarchy, PartGroups, PartPolicies, PartAuth, PartServicePolicy}
+}
+
+type RestoreOptions struct {
+	// What to restore; everything if empty.
+	Parts []Part
+}
+
+func (o RestoreOptions) has(p Part) bool { return len(o.Parts) == 0 || slices.Contains(o.Parts, p) }
+
+// RestorePlan is what Restore would write.  Printing it is a dry run.
+type RestorePlan struct {
+	// Folders, projects, service principals, groups, memberships and
+	// policies, matched by name as the reconcile package does.
+	Resources *reconcile.Plan
+
+	// Password policy, auth conditions and service policy, as a diff from
+	// the live settings to the snapshot.
+	Settings *Diff
+
+	// Parts of the snapshot that cannot be restored, such as members who
+	// are no longer users.
+	Skipped []string
+
+	target *Snapshot
+}
+
+func (p *RestorePlan) Empty() bool { return p.Resources.Empty() && p.Settings.Empty() }
+
+func (p *RestorePlan) String() string {
+	var b strings.Builder
+	if p.Empty() {
+		b.WriteString("No changes.\n")
+	} else {
+		if !p.Resources.Empty() {
+			b.WriteString(p.Resources.String())
+		}
+		if !p.Settings.Empty() {
+			if !p.Resources.Empty() {
+				b.WriteString("\n")
+			}
+			b.WriteString(p.Settings.String())
+		}
+	}
+	if len(p.Skipped) > 0 {
+		b.WriteString("\nNot restored:\n")
+		for _, i := range p.Skipped {
+			fmt.Fprintf(&b, "    %s\n", i)
+		}
+	}
+	return b.String()
+}
+
+// Restorer writes a snapshot back.  Resources are matched by name, not ID,
+// so a deleted group or folder is recreated and bindings follow it.  Users
+// cannot be recreated, their passwords not being in the snapshot, and
+// nothing the snapshot lacks is deleted.
+type Restorer struct {
+	*Exporter
+	reconciler *reconcile.Reconciler
+}
+
+func NewRestorer(client *v1.Client) *Restorer {
+	return &Restorer{Exporter: NewExporter(client), reconciler: reconcile.NewReconciler(client)}
+}
+
+// Plan compares s with the organization as it is now.
+func (r *Restorer) Plan(ctx context.Context, s *Snapshot, opts RestoreOptions) (*RestorePlan, error) {
+	for _, p := range opts.Parts {
+		if !slices.Contains(p.AllValues(), p) {
+			return nil, common.NewError("Snapshot.Plan", errors.Errorf("unknown part %q", p))
+		}
+	}
+	live, err := r.Export(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := stateBuilder{target: s, live: live, opts: opts}
+	state := b.build()
+	resources, err := r.reconciler.Plan(ctx, state, reconcile.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := Compare(live, s)
+	if err != nil {
+		return nil, err
+	}
+	var settings Diff
+	for _, c := range diff.Changes {
+		switch {
+		case opts.has(PartAuth) && (c.Section == SectionPasswordPolicy || c.Section == SectionAuthConditions):
+			settings.add(c)
+		case opts.has(PartServicePolicy) && c.Section == SectionServicePolicy:
+			// rules can only be written while the service policy is
+			// enabled, and enabling it just for that would enforce them
+			if !s.ServicePolicy.Enabled && !live.ServicePolicy.Enabled && c.Name != servicePolicyStatus {
+				b.skip("%s of the service policy, which is disabled", c.Name)
+				continue
+			}
+			settings.add(c)
+		}
+	}
+	return &RestorePlan{Resources: resources, Settings: &settings, Skipped: b.skipped, target: s}, nil
+}
+
+// Restore carries out plan: resources first, then settings.  It stops at the
+// first failure.
+//
+// Service policy rules can only be written while the service policy is
+// enabled, so when the snapshot enables it, it is enabled before the rules
+// are replaced: until then, or for good if writing them fails, the rules
+// already there are enforced.
+func (r *Restorer) Restore(ctx context.Context, plan *RestorePlan) error {
+	if err := r.reconciler.Apply(ctx, plan.Resources); err != nil {
+		return err
+	}
+
+	changed := func(section Section) bool {
+		return slices.ContainsFunc(plan.Settings.Changes, func(c Change) bool { return c.Section == section })
+	}
+	s := plan.target
+	if changed(SectionPasswordPolicy) {
+		if _, err := r.auth.UpdatePasswordPolicy(ctx, s.PasswordPolicy); err != nil {
+			return err
+		}
+	}
+	if changed(SectionAuthConditions) {
+		if _, err := r.auth.UpdateAuthConditions(ctx, &s.AuthConditions); err != nil {
+			return err
+		}
+	}
+	if !changed(SectionServicePolicy) {
+		return nil
+	}
+
+	// rules can only be written while the service policy is enabled
+	enabled, err := r.policy.IsEnabled(ctx)
+	if err != nil {
+		return err
+	}
+	if s.ServicePolicy.Enabled && !enabled {
+		if err := r.policy.Enable(ctx); err != nil {
+			return err
+		}
+		enabled = true
+	}
+	if enabled && slices.ContainsFunc(plan.Settings.Changes, func(c Change) bool { return c.Section == SectionServicePolicy && c.Name != servicePolicyStatus }) {
+		rules := make([]v1.Rule, 0, len(s.ServicePolicy.Rules))
+		for _, i := range s.ServicePolicy.Rules {
+			rules = append(rules, v1.Rule{Code: i.Code, Spec: i.Spec, DryRunSpec: i.DryRunSpec, IsActive: i.IsActive, IsDryRun: i.IsDryRun})
+		}
+		if _, err := r.organization.UpdateServicePolicy(ctx, rules); err != nil {
+			return err
+		}
+	}
+	if !s.ServicePolicy.Enabled && enabled {
+		return r.policy.Disable(ctx)
+	}
+	return nil
+}
+
+// turns the selected parts of target into a reconcile.State
+type stateBuilder struct {
+	target, live *Snapshot
+	opts         RestoreOptions
+	skipped      []string
+}
+
+func (b *stateBuilder) skip(format string, args ...any) {
+	b.skipped = append(b.skipped, fmt.Sprintf(format, args...))
+}
+
+func (b *stateBuilder) build() *reconcile.State {
+	var state reconcile.State
+	t, live := b.target, b.live
+	policies := b.opts.has(PartPolicies)
+
+	for _, f := range t.Folders {
+		path := t.folderPath(f.Folder.ID)
+		folder := reconcile.Folder{Path: strings.TrimPrefix(path, "/"), Description: f.Folder.Description}
+		if !b.opts.has(PartHierarchy) {
+			if !policies {
+				break
+			}
+			// only there to carry the policy; keep it as it is
+			i := slices.IndexFunc(live.Folders, func(l Folder) bool { return live.folderPath(l.Folder.ID) == path })
+			if i < 0 {
+				b.skip("policy of folder %s, which no longer exists", path)
+				continue
+			}
+			folder.Description = live.Folders[i].Folder.Description
+		}
+		if policies {
+			folder.Policy = b.iamPolicy("folder "+path, f.IAMPolicy)
+		}
+		state.Folders = append(state.Folders, folder)
+	}
+
+	for _, p := range t.Projects {
+		project := reconcile.Project{Code: p.Project.Code, Name: p.Project.Name, Description: p.Project.Description}
+		if !p.Project.ParentFolderID.Null {
+			project.Folder = strings.TrimPrefix(t.folderPath(p.Project.ParentFolderID.Value), "/")
+		}
+		if !b.opts.has(PartHierarchy) {
+			if !policies {
+				break
+			}
+			i := slices.IndexFunc(live.Projects, func(l Project) bool { return l.Project.Code == p.Project.Code })
+			if i < 0 {
+				b.skip("policy of project %s, which no longer exists", p.Project.Code)
+				continue
+			}
+			l := live.Projects[i].Project
+			project.Name, project.Description, project.Folder = l.Name, l.Description, ""
+			if !l.ParentFolderID.Null {
+				project.Folder = strings.TrimPrefix(live.folderPath(l.ParentFolderID.Value), "/")
+			}
+		}
+		if policies {
+			project.Policy = b.iamPolicy("project "+p.Project.Code, p.IAMPolicy)
+		}
+		state.Projects = append(state.Projects, project)
+	}
+
+	if b.opts.has(PartHierarchy) {
+		for _, sp := range t.ServicePrincipals {
+			if p, ok := b.principal(common.ServicePrincipalPrincipal(sp.ServicePrincipal.ID)); ok {
+				state.ServicePrincipals = append(state.ServicePrincipals, reconcile.ServicePrincipal{
+					Project: p.Project, Name: p.Name, Description: sp.ServicePrincipal.Description,
+				})
+			}
+		}
+	}
+
+	if b.opts.has(PartGroups) {
+		for _, g := range t.Groups {
+			members := []string{}
+			for _, id := range g.Members {
+				if p, ok := b.principal(common.UserPrincipal(id)); ok {
+					members = append(members, p.Name)
+				} else {
+					b.skip("member %s of group %s", t.principal(common.UserPrincipal(id)), g.Group.Name)
+				}
+			}
+			state.Groups = append(state.Groups, reconcile.Group{Name: g.Group.Name, Description: g.Group.Description, Members: members})
+		}
+	}
+
+	if policies {
+		state.Organization = b.iamPolicy("organization", t.IAMPolicy)
+		state.IDPolicy = policydoc.Policy{}
+		for _, i := range t.IDPolicy {
+			state.IDPolicy = append(state.IDPolicy, b.binding("id-policy", i.Role.Value.ID.Value, i.Principals))
+		}
+	}
+	return &state
+}
+
+func (b *stateBuilder) iamPolicy(scope string, bindings []v1.IamPolicy) policydoc.Policy {
+	ret := policydoc.Policy{}
+	for _, i := range bindings {
+		ret = append(ret, b.binding(scope, i.Role.Value.ID.Value, i.Principals))
+	}
+	return ret
+}
+
+func (b *stateBuilder) binding(scope, role string, principals []v1.Principal) policydoc.Binding {
+	ret := policydoc.Binding{Role: role, Principals: []policydoc.Principal{}}
+	for _, i := range principals {
+		if p, ok := b.principal(i); ok {
+			ret.Principals = append(ret.Principals, p)
+		} else {
+			b.skip("%s: %s to %s", scope, role, b.target.principal(i))
+		}
+	}
+	return ret
+}
+
+// p of the snapshot, by name, if it exists now or is about to be created
+func (b *stateBuilder) principal(p v1.Principal) (policydoc.Principal, bool) {
+	t, live := b.target, b.live
+	ret := policydoc.Principal{Type: common.PrincipalTypeOf(p)}
+	name := strings.TrimPrefix(t.principal(p), string(ret.Type)+" ")
+	if strings.HasPrefix(name, "#") {
+		return ret, false // not even the snapshot knows it
+	}
+
+	switch ret.Type {
+	case common.PrincipalTypeUser:
+		ret.Name = name
+		return ret, slices.ContainsFunc(live.Users, func(u v1.User) bool { return u.Code == name })
+	case common.PrincipalTypeGroup:
+		ret.Name = name
+		return ret, b.opts.has(PartGroups) || slices.ContainsFunc(live.Groups, func(g Group) bool { return g.Group.Name == name })
+	case common.PrincipalTypeServicePrincipal:
+		ret.Project, ret.Name, _ = strings.Cut(name, "/")
+		if strings.HasPrefix(ret.Project, "#") {
+			return ret, false
+		}
+		return ret, b.opts.has(PartHierarchy) || slices.ContainsFunc(live.ServicePrincipals, func(sp ServicePrincipal) bool {
+			return live.principal(common.ServicePrincipalPrincipal(sp.ServicePrincipal.ID)) == "service-principal "+name
+		})
+	}
+	return ret, false
+}
@@ -0,0 +1,113 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot_test
+
+import (
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/group"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/reconcile"
+	. "github.com/sacloud/iam-api-go/snapshot"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestore(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	populate(t, client)
+
+	_, err := iam.NewAuthOp(client).UpdatePasswordPolicy(ctx, v1.PasswordPolicy{MinLength: 10})
+	assert.NoError(err)
+	s, err := NewExporter(client).Export(ctx)
+	assert.NoError(err)
+
+	// lose the group, and with it the binding on the project
+	groups := iam.NewGroupOp(client)
+	assert.NoError(groups.Delete(ctx, s.Groups[0].Group.ID))
+	_, err = iam.NewAuthOp(client).UpdatePasswordPolicy(ctx, v1.PasswordPolicy{MinLength: 16})
+	assert.NoError(err)
+
+	r := NewRestorer(client)
+	plan, err := r.Plan(ctx, s, RestoreOptions{Parts: []Part{PartGroups}})
+	assert.NoError(err)
+	assert.Equal(1, plan.Resources.Count(reconcile.ActionCreate))
+	assert.Equal(1, plan.Resources.Count(reconcile.ActionUpdate)) // memberships
+	assert.True(plan.Settings.Empty())
+
+	plan, err = r.Plan(ctx, s, RestoreOptions{})
+	assert.NoError(err)
+	assert.Contains(plan.String(), `~ iam-policy "project web"`)
+	assert.Contains(plan.String(), "~ password-policy\n    min_length: 16 => 10")
+
+	// a dry run changes nothing
+	g, err := group.ListAll(ctx, groups, group.ListParams{})
+	assert.NoError(err)
+	assert.Empty(g)
+
+	assert.NoError(r.Restore(ctx, plan))
+
+	g, err = group.ListAll(ctx, groups, group.ListParams{})
+	assert.NoError(err)
+	assert.Len(g, 1)
+	policy, err := iam.NewIAMPolicyOp(client).ReadProjectPolicy(ctx, s.Projects[0].Project.ID)
+	assert.NoError(err)
+	assert.Equal([]v1.IamPolicy{iam.IAMBinding("admin", iam.GroupPrincipal(g[0].ID))}, policy)
+	pp, err := iam.NewAuthOp(client).ReadPasswordPolicy(ctx)
+	assert.NoError(err)
+	assert.Equal(10, pp.MinLength)
+
+	plan, err = r.Plan(ctx, s, RestoreOptions{})
+	assert.NoError(err)
+	assert.True(plan.Empty(), plan.String())
+}
+
+func TestRestore_Skipped(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	populate(t, client)
+
+	s, err := NewExporter(client).Export(ctx)
+	assert.NoError(err)
+	assert.NoError(iam.NewUserOp(client).Delete(ctx, s.Users[0].ID))
+
+	plan, err := NewRestorer(client).Plan(ctx, s, RestoreOptions{Parts: []Part{PartGroups}})
+	assert.NoError(err)
+	assert.Equal([]string{"member user alice of group admins"}, plan.Skipped)
+	assert.Contains(plan.String(), "Not restored:\n    member user alice of group admins\n")
+
+	_, err = NewRestorer(client).Plan(ctx, s, RestoreOptions{Parts: []Part{"users"}})
+	assert.ErrorContains(err, `unknown part "users"`)
+}
+
+func TestRestore_DisabledServicePolicy(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+
+	s, err := NewExporter(client).Export(ctx)
+	assert.NoError(err)
+	assert.False(s.ServicePolicy.Enabled)
+	s.ServicePolicy.Rules = append(s.ServicePolicy.Rules, v1.RuleResponse{Code: v1.NewOptString("restrict-zone"), IsActive: v1.NewOptBool(true)})
+
+	plan, err := NewRestorer(client).Plan(ctx, s, RestoreOptions{Parts: []Part{PartServicePolicy}})
+	assert.NoError(err)
+	assert.True(plan.Empty(), plan.String())
+	assert.Equal([]string{"rule restrict-zone of the service policy, which is disabled"}, plan.Skipped)
+}
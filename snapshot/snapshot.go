@@ -0,0 +1,314 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot captures the IAM configuration of an organization at a
+// point in time, as a single JSON document, for backup and audit.
+//
+// Resources are recorded as the API returns them, minus anything secret:
+// users carry no password, SCIM configurations no token, and service
+// principal keys only their public half.  Lists are sorted by ID so that two
+// snapshots of an unchanged organization are identical but for TakenAt.
+package snapshot
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/auth"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/group"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/idpolicy"
+	"github.com/sacloud/iam-api-go/apis/organization"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/scim"
+	"github.com/sacloud/iam-api-go/apis/servicepolicy"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/sso"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// Version is the only format version understood so far.
+const Version = 1
+
+type Snapshot struct {
+	Version int       `json:"version"`
+	TakenAt time.Time `json:"taken_at"`
+
+	Organization   v1.Organization   `json:"organization"`
+	PasswordPolicy v1.PasswordPolicy `json:"password_policy"`
+	AuthConditions v1.AuthConditions `json:"auth_conditions"`
+	ServicePolicy  ServicePolicy     `json:"service_policy"`
+
+	IAMPolicy []v1.IamPolicy `json:"iam_policy"` // of the organization
+	IDPolicy  []v1.IdPolicy  `json:"id_policy"`
+
+	Folders           []Folder           `json:"folders"`
+	Projects          []Project          `json:"projects"`
+	Users             []v1.User          `json:"users"`
+	Groups            []Group            `json:"groups"`
+	ServicePrincipals []ServicePrincipal `json:"service_principals"`
+
+	SSOProfiles        []v1.SSOProfile            `json:"sso_profiles"`
+	SCIMConfigurations []v1.ScimConfigurationBase `json:"scim_configurations"`
+}
+
+type ServicePolicy struct {
+	Enabled bool              `json:"enabled"`
+	Rules   []v1.RuleResponse `json:"rules"`
+}
+
+type Folder struct {
+	Folder    v1.Folder      `json:"folder"`
+	IAMPolicy []v1.IamPolicy `json:"iam_policy"`
+}
+
+type Project struct {
+	Project   v1.Project     `json:"project"`
+	IAMPolicy []v1.IamPolicy `json:"iam_policy"`
+}
+
+type Group struct {
+	Group   v1.Group `json:"group"`
+	Members []int    `json:"members"` // user IDs
+}
+
+type ServicePrincipal struct {
+	ServicePrincipal v1.ServicePrincipal      `json:"service_principal"`
+	Keys             []v1.ServicePrincipalKey `json:"keys"`
+}
+
+// Exporter takes snapshots through the regular API wrappers.  The caller
+// needs read access to everything listed in Snapshot.
+type Exporter struct {
+	organization organization.OrganizationAPI
+	auth         auth.AuthAPI
+	policy       servicepolicy.ServicePolicyAPI
+	iamPolicy    iampolicy.IAMPolicyAPI
+	idPolicy     idpolicy.IDPolicyAPI
+	folders      folder.FolderAPI
+	projects     project.ProjectAPI
+	users        user.UserAPI
+	groups       group.GroupAPI
+	principals   serviceprincipal.ServicePrincipalAPI
+	sso          sso.SSOAPI
+	scim         scim.ScimAPI
+}
+
+func NewExporter(client *v1.Client) *Exporter {
+	return &Exporter{
+		organization: organization.NewOrganizationOp(client),
+		auth:         auth.NewAuthOp(client),
+		policy:       servicepolicy.NewServicePolicyOp(client),
+		iamPolicy:    iampolicy.NewIAMPolicyOp(client),
+		idPolicy:     idpolicy.NewIDPolicyOp(client),
+		folders:      folder.NewFolderOp(client),
+		projects:     project.NewProjectOp(client),
+		users:        user.NewUserOp(client),
+		groups:       group.NewGroupOp(client),
+		principals:   serviceprincipal.NewServicePrincipalOp(client),
+		sso:          sso.NewSSOOp(client),
+		scim:         scim.NewScimOp(client),
+	}
+}
+
+// Export reads the whole configuration.  The API has no transactions, so
+// changes made while it runs may or may not be seen.
+func (e *Exporter) Export(ctx context.Context) (*Snapshot, error) {
+	s := Snapshot{Version: Version, TakenAt: time.Now().UTC()}
+	for _, step := range []func(context.Context, *Snapshot) error{
+		e.settings,
+		e.policies,
+		e.hierarchy,
+		e.identities,
+		e.federation,
+	} {
+		if err := step(ctx, &s); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+func (e *Exporter) settings(ctx context.Context, s *Snapshot) error {
+	o, err := e.organization.Read(ctx)
+	if err != nil {
+		return err
+	}
+	pp, err := e.auth.ReadPasswordPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	ac, err := e.auth.ReadAuthConditions(ctx)
+	if err != nil {
+		return err
+	}
+	enabled, err := e.policy.IsEnabled(ctx)
+	if err != nil {
+		return err
+	}
+	rules, err := e.organization.ReadServicePolicy(ctx, organization.GetServicePolicyParams{})
+	if err != nil {
+		return err
+	}
+	s.Organization, s.PasswordPolicy, s.AuthConditions = *o, *pp, *ac
+	s.ServicePolicy = ServicePolicy{Enabled: enabled, Rules: rules}
+	return nil
+}
+
+func (e *Exporter) policies(ctx context.Context, s *Snapshot) (err error) {
+	if s.IAMPolicy, err = e.iamPolicy.ReadOrganizationPolicy(ctx); err != nil {
+		return err
+	}
+	s.IDPolicy, err = e.idPolicy.ReadOrganizationIdPolicy(ctx)
+	return err
+}
+
+func (e *Exporter) hierarchy(ctx context.Context, s *Snapshot) error {
+	folders, err := folder.ListAll(ctx, e.folders, folder.ListParams{})
+	if err != nil {
+		return err
+	}
+	for _, f := range sortByID(folders, func(f *v1.Folder) int { return f.ID }) {
+		b, err := e.iamPolicy.ReadFolderPolicy(ctx, f.ID)
+		if err != nil {
+			return err
+		}
+		s.Folders = append(s.Folders, Folder{Folder: f, IAMPolicy: b})
+	}
+
+	projects, err := project.ListAll(ctx, e.projects, project.ListParams{})
+	if err != nil {
+		return err
+	}
+	for _, p := range sortByID(projects, func(p *v1.Project) int { return p.ID }) {
+		b, err := e.iamPolicy.ReadProjectPolicy(ctx, p.ID)
+		if err != nil {
+			return err
+		}
+		s.Projects = append(s.Projects, Project{Project: p, IAMPolicy: b})
+	}
+	return nil
+}
+
+func (e *Exporter) identities(ctx context.Context, s *Snapshot) error {
+	users, err := user.ListAll(ctx, e.users, user.ListParams{})
+	if err != nil {
+		return err
+	}
+	s.Users = sortByID(users, func(u *v1.User) int { return u.ID })
+
+	groups, err := group.ListAll(ctx, e.groups, group.ListParams{})
+	if err != nil {
+		return err
+	}
+	for _, g := range sortByID(groups, func(g *v1.Group) int { return g.ID }) {
+		m, err := e.groups.ReadMemberships(ctx, g.ID)
+		if err != nil {
+			return err
+		}
+		ids := make([]int, 0, len(m))
+		for _, i := range m {
+			ids = append(ids, i.ID)
+		}
+		slices.Sort(ids)
+		s.Groups = append(s.Groups, Group{Group: g, Members: ids})
+	}
+
+	sps, err := serviceprincipal.ListAll(ctx, e.principals, serviceprincipal.ListParams{})
+	if err != nil {
+		return err
+	}
+	for _, sp := range sortByID(sps, func(sp *v1.ServicePrincipal) int { return sp.ID }) {
+		keys, err := serviceprincipal.ListAllKeys(ctx, e.principals, sp.ID, serviceprincipal.ListKeysParams{})
+		if err != nil {
+			return err
+		}
+		slices.SortFunc(keys, func(a, b v1.ServicePrincipalKey) int { return cmp.Compare(a.ID.String(), b.ID.String()) })
+		s.ServicePrincipals = append(s.ServicePrincipals, ServicePrincipal{ServicePrincipal: sp, Keys: keys})
+	}
+	return nil
+}
+
+func (e *Exporter) federation(ctx context.Context, s *Snapshot) error {
+	profiles, err := sso.ListAll(ctx, e.sso, nil, nil)
+	if err != nil {
+		return err
+	}
+	s.SSOProfiles = sortByID(profiles, func(p *v1.SSOProfile) int { return p.ID })
+
+	configs, err := scim.ListAll(ctx, e.scim, scim.ListParams{})
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(configs, func(a, b v1.ScimConfigurationBase) int { return cmp.Compare(a.ID.String(), b.ID.String()) })
+	s.SCIMConfigurations = configs
+	return nil
+}
+
+func sortByID[T any](s []T, id func(*T) int) []T {
+	slices.SortFunc(s, func(a, b T) int { return cmp.Compare(id(&a), id(&b)) })
+	return s
+}
+
+// Decode reads a snapshot written by Encode.
+func Decode(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, common.NewError("Snapshot.Decode", err)
+	}
+	if s.Version != Version {
+		return nil, common.NewError("Snapshot.Decode", errors.Errorf("unsupported snapshot version %d (want %d)", s.Version, Version))
+	}
+	return &s, nil
+}
+
+// Encode writes s as indented JSON.
+func (s *Snapshot) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return common.NewError("Snapshot.Encode", err)
+	}
+	return nil
+}
+
+func ReadFile(name string) (*Snapshot, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, common.NewError("Snapshot.ReadFile", err)
+	}
+	defer func() { _ = f.Close() }()
+	return Decode(f)
+}
+
+func (s *Snapshot) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return common.NewError("Snapshot.WriteFile", err)
+	}
+	err = s.Encode(f)
+	if e := f.Close(); err == nil && e != nil {
+		err = common.NewError("Snapshot.WriteFile", e)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/scim"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	. "github.com/sacloud/iam-api-go/snapshot"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func populate(t *testing.T, client *v1.Client) (secret string) {
+	assert := require.New(t)
+	ctx := t.Context()
+
+	f, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "prod"})
+	assert.NoError(err)
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "web", Name: "web", ParentFolderID: &f.ID})
+	assert.NoError(err)
+	u, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Code: "alice", Name: "Alice", Password: "hunter2hunter2"})
+	assert.NoError(err)
+	g, err := iam.NewGroupOp(client).Create(ctx, "admins", "")
+	assert.NoError(err)
+	_, err = iam.NewGroupOp(client).UpdateMemberships(ctx, g.ID, []int{u.ID})
+	assert.NoError(err)
+	_, err = iam.NewServicePrincipalOp(client).Create(ctx, serviceprincipal.CreateParams{ProjectID: p.ID, Name: "deployer"})
+	assert.NoError(err)
+	_, err = iam.NewIAMPolicyOp(client).UpdateProjectPolicy(ctx, p.ID, []v1.IamPolicy{iam.IAMBinding("admin", iam.GroupPrincipal(g.ID))})
+	assert.NoError(err)
+	c, err := iam.NewScimOp(client).Create(ctx, scim.CreateParams{Name: "okta"})
+	assert.NoError(err)
+	return c.SecretToken
+}
+
+func TestExport(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	secret := populate(t, client)
+
+	s, err := NewExporter(client).Export(t.Context())
+	assert.NoError(err)
+	assert.Equal(Version, s.Version)
+	assert.False(s.TakenAt.IsZero())
+
+	assert.Len(s.Folders, 1)
+	assert.Equal("prod", s.Folders[0].Folder.Name)
+	assert.Len(s.Projects, 1)
+	assert.Len(s.Projects[0].IAMPolicy, 1)
+	assert.Equal("admin", s.Projects[0].IAMPolicy[0].Role.Value.ID.Value)
+	assert.Len(s.Groups, 1)
+	assert.Equal([]int{s.Users[0].ID}, s.Groups[0].Members)
+	assert.Len(s.ServicePrincipals, 1)
+	assert.Empty(s.ServicePrincipals[0].Keys)
+	assert.Len(s.SCIMConfigurations, 1)
+
+	var buf bytes.Buffer
+	assert.NoError(s.Encode(&buf))
+	assert.NotContains(buf.String(), secret)
+	assert.NotContains(buf.String(), "hunter2")
+}
+
+func TestEncodeDecode(t *testing.T) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	populate(t, client)
+
+	s, err := NewExporter(client).Export(t.Context())
+	assert.NoError(err)
+
+	name := filepath.Join(t.TempDir(), "snapshot.json")
+	assert.NoError(s.WriteFile(name))
+	read, err := ReadFile(name)
+	assert.NoError(err)
+	assert.Equal(s.TakenAt.Unix(), read.TakenAt.Unix())
+	read.TakenAt = s.TakenAt
+	assert.Equal(s, read)
+
+	_, err = Decode(strings.NewReader(`{"version": 2}`))
+	assert.ErrorContains(err, "unsupported snapshot version 2")
+}
@@ -0,0 +1,464 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package teardown deletes a folder together with everything below it.
+//
+// The API only deletes empty things: a folder without subfolders or
+// projects, a project without service principals or API keys, a service
+// principal without keys.  A Plan lists all of that, bottom-up, and Apply
+// works through it.  Bindings that name the doomed service principals in the
+// organization policy, the ID policy or the policy of any folder or project
+// that stays are removed as well; policies attached to the deleted folders
+// and projects go away with them.
+package teardown
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-faster/errors"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/iampolicy"
+	"github.com/sacloud/iam-api-go/apis/idpolicy"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/projectapikey"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+	"github.com/sacloud/iam-api-go/hierarchy"
+)
+
+type Kind string
+
+const (
+	KindBinding             Kind = "binding"
+	KindServicePrincipalKey Kind = "service-principal-key"
+	KindAPIKey              Kind = "api-key"
+	KindServicePrincipal    Kind = "service-principal"
+	KindProject             Kind = "project"
+	KindFolder              Kind = "folder"
+)
+
+// Item is one deletion.
+type Item struct {
+	Kind    Kind
+	Name    string   // for humans: a path, or what the thing belongs to
+	Details []string // what goes with it, such as its policy
+
+	needs []int // items that must go first, as indices into Plan.Items
+	apply func(context.Context) error
+}
+
+func (i *Item) String() string { return fmt.Sprintf("%s %s", i.Kind, i.Name) }
+
+// Plan is everything to delete for a folder.  An item only comes after
+// those it needs.
+type Plan struct {
+	Root  *hierarchy.Node
+	Items []Item
+}
+
+// Count is the number of items of kind.
+func (p *Plan) Count(kind Kind) int {
+	n := 0
+	for _, i := range p.Items {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// String is the dry-run report: a line per item, then the totals.
+func (p *Plan) String() string {
+	var b strings.Builder
+	for _, i := range p.Items {
+		fmt.Fprintf(&b, "- %s\n", &i)
+		for _, d := range i.Details {
+			fmt.Fprintf(&b, "    %s\n", d)
+		}
+	}
+	fmt.Fprintf(&b, "\nDelete %s: %d folders, %d projects, %d service principals, %d keys, %d API keys, %d policy edits.\n",
+		p.Root.Path(), p.Count(KindFolder), p.Count(KindProject), p.Count(KindServicePrincipal),
+		p.Count(KindServicePrincipalKey), p.Count(KindAPIKey), p.Count(KindBinding))
+	return b.String()
+}
+
+type Options struct {
+	// How many deletions may run at once; 1 if zero or less.
+	Concurrency int
+}
+
+// Failure is an item Apply could not delete.
+type Failure struct {
+	Item string
+	Err  error
+}
+
+// Report tells what Apply did.  Every item of the plan ends up in exactly one
+// of the lists, in plan order.
+type Report struct {
+	Deleted []string
+	Failed  []Failure
+
+	// Not attempted, because something that had to go first failed, or
+	// because the context was done.
+	Skipped []string
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, f := range r.Failed {
+		fmt.Fprintf(&b, "failed: %s: %s\n", f.Item, f.Err)
+	}
+	for _, i := range r.Skipped {
+		fmt.Fprintf(&b, "skipped: %s\n", i)
+	}
+	fmt.Fprintf(&b, "%d deleted, %d failed, %d skipped.\n", len(r.Deleted), len(r.Failed), len(r.Skipped))
+	return b.String()
+}
+
+type Deleter struct {
+	folders    folder.FolderAPI
+	projects   project.ProjectAPI
+	principals serviceprincipal.ServicePrincipalAPI
+	apiKeys    projectapikey.ProjectAPIKeyAPI
+	iamPolicy  iampolicy.IAMPolicyAPI
+	idPolicy   idpolicy.IDPolicyAPI
+}
+
+func NewDeleter(client *v1.Client) *Deleter {
+	return &Deleter{
+		folders:    folder.NewFolderOp(client),
+		projects:   project.NewProjectOp(client),
+		principals: serviceprincipal.NewServicePrincipalOp(client),
+		apiKeys:    projectapikey.NewProjectAPIKeyOp(client),
+		iamPolicy:  iampolicy.NewIAMPolicyOp(client),
+		idPolicy:   idpolicy.NewIDPolicyOp(client),
+	}
+}
+
+// Plan lists what deleting the folder takes.  It changes nothing, so
+// printing the plan is a dry run.
+func (d *Deleter) Plan(ctx context.Context, folderID int) (*Plan, error) {
+	tree, err := hierarchy.Fetch(ctx, d.folders, d.projects)
+	if err != nil {
+		return nil, err
+	}
+	root := tree.Folder(folderID)
+	if root == nil {
+		return nil, common.NewError("Teardown.Plan", errors.Errorf("folder %d not found", folderID))
+	}
+
+	p := planner{Deleter: d, plan: &Plan{Root: root}, index: map[string]int{}}
+	nodes := append([]*hierarchy.Node{root}, root.Descendants()...)
+	if err := p.collect(ctx, nodes); err != nil {
+		return nil, err
+	}
+	if err := p.unbind(ctx, tree, nodes); err != nil {
+		return nil, err
+	}
+
+	// projects, then folders deepest first, so that the children of a
+	// folder are already in the plan when it is added
+	slices.SortStableFunc(nodes, func(a, b *hierarchy.Node) int {
+		if a.Kind != b.Kind {
+			return strings.Compare(string(b.Kind), string(a.Kind))
+		}
+		return len(b.Ancestors()) - len(a.Ancestors())
+	})
+	for _, n := range nodes {
+		var err error
+		if n.Kind == hierarchy.KindProject {
+			err = p.project(ctx, n)
+		} else {
+			err = p.folder(ctx, n)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.plan, nil
+}
+
+// Apply deletes the items of plan, bottom-up, up to opts.Concurrency at a
+// time.  When an item fails, the ones that depend on it are skipped and the
+// rest carry on.  The report is complete even when the error is not nil.
+func (d *Deleter) Apply(ctx context.Context, plan *Plan, opts Options) (*Report, error) {
+	const (
+		skipped = iota
+		deleted
+		failed
+	)
+	state := make([]int, len(plan.Items))
+	errs := make([]error, len(plan.Items))
+	done := make([]chan struct{}, len(plan.Items))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	sem := make(chan struct{}, max(opts.Concurrency, 1))
+
+	// every item waits for those it needs, then for a free slot
+	var wg sync.WaitGroup
+	for i := range plan.Items {
+		it := &plan.Items[i]
+		wg.Go(func() {
+			defer close(done[i])
+			for _, j := range it.needs {
+				<-done[j]
+				if state[j] != deleted {
+					return
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				return
+			}
+			if err := it.apply(ctx); err != nil {
+				state[i], errs[i] = failed, err
+			} else {
+				state[i] = deleted
+			}
+		})
+	}
+	wg.Wait()
+
+	var r Report
+	for i, it := range plan.Items {
+		switch state[i] {
+		case deleted:
+			r.Deleted = append(r.Deleted, it.String())
+		case failed:
+			r.Failed = append(r.Failed, Failure{Item: it.String(), Err: errs[i]})
+		default:
+			r.Skipped = append(r.Skipped, it.String())
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return &r, common.NewError("Teardown.Apply", err)
+	}
+	if len(r.Failed) > 0 {
+		joined := make([]error, len(r.Failed))
+		for i, f := range r.Failed {
+			joined[i] = errors.Wrap(f.Err, f.Item)
+		}
+		return &r, common.NewError("Teardown.Apply", errors.Join(joined...))
+	}
+	return &r, nil
+}
+
+type planner struct {
+	*Deleter
+	plan *Plan
+
+	// position in plan.Items by key(), for the items others depend on
+	index map[string]int
+
+	principals []v1.ServicePrincipal
+	apiKeys    []v1.ProjectApiKey
+	bindings   []int // the policy edits, which the service principals wait for
+}
+
+func key(kind hierarchy.Kind, id int) string { return fmt.Sprintf("%s %d", kind, id) }
+
+func (p *planner) add(it Item) int {
+	p.plan.Items = append(p.plan.Items, it)
+	return len(p.plan.Items) - 1
+}
+
+// the service principals and API keys of the projects among nodes
+func (p *planner) collect(ctx context.Context, nodes []*hierarchy.Node) error {
+	projects := map[int]bool{}
+	for _, n := range nodes {
+		if n.Kind == hierarchy.KindProject {
+			projects[n.ID()] = true
+			sp, err := serviceprincipal.ListAll(ctx, p.Deleter.principals, serviceprincipal.ListParams{ProjectID: &n.Project.ID})
+			if err != nil {
+				return err
+			}
+			p.principals = append(p.principals, sp...)
+		}
+	}
+	keys, err := projectapikey.ListAll(ctx, p.Deleter.apiKeys, projectapikey.ListParams{})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if projects[k.ProjectID] {
+			p.apiKeys = append(p.apiKeys, k)
+		}
+	}
+	return nil
+}
+
+// one item per policy outside the doomed nodes that names a doomed service
+// principal
+func (p *planner) unbind(ctx context.Context, tree *hierarchy.Tree, nodes []*hierarchy.Node) error {
+	doomed := func(x v1.Principal) bool {
+		return common.PrincipalTypeOf(x) == common.PrincipalTypeServicePrincipal &&
+			slices.ContainsFunc(p.principals, func(sp v1.ServicePrincipal) bool { return sp.ID == x.ID.Value })
+	}
+	if len(p.principals) == 0 {
+		return nil
+	}
+
+	scopes := []iampolicy.Scope{iampolicy.OrganizationScope()}
+	names := []string{"organization IAM policy"}
+	for _, n := range tree.Find(func(n *hierarchy.Node) bool { return !slices.Contains(nodes, n) }) {
+		if n.Kind == hierarchy.KindProject {
+			scopes = append(scopes, iampolicy.ProjectScope(n.ID()))
+		} else {
+			scopes = append(scopes, iampolicy.FolderScope(n.ID()))
+		}
+		names = append(names, "IAM policy of "+n.Path())
+	}
+	for i, s := range scopes {
+		b, err := iampolicy.ReadPolicy(ctx, p.iamPolicy, s)
+		if err != nil {
+			return err
+		}
+		var details []string
+		for _, x := range b {
+			for _, y := range slices.DeleteFunc(slices.Clone(x.Principals), func(y v1.Principal) bool { return !doomed(y) }) {
+				details = append(details, fmt.Sprintf("%s: service principal #%d", x.Role.Value.ID.Value, y.ID.Value))
+			}
+		}
+		if len(details) > 0 {
+			p.bindings = append(p.bindings, p.add(Item{Kind: KindBinding, Name: names[i], Details: details, apply: func(ctx context.Context) error {
+				_, err := iampolicy.ModifyPolicy(ctx, p.iamPolicy, s, func(b []v1.IamPolicy) []v1.IamPolicy {
+					for j := range b {
+						b[j].Principals = slices.DeleteFunc(b[j].Principals, doomed)
+					}
+					return slices.DeleteFunc(b, func(x v1.IamPolicy) bool { return len(x.Principals) == 0 })
+				})
+				return err
+			}}))
+		}
+	}
+
+	b, err := p.idPolicy.ReadOrganizationIdPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	var details []string
+	for _, x := range b {
+		for _, y := range slices.DeleteFunc(slices.Clone(x.Principals), func(y v1.Principal) bool { return !doomed(y) }) {
+			details = append(details, fmt.Sprintf("%s: service principal #%d", x.Role.Value.ID.Value, y.ID.Value))
+		}
+	}
+	if len(details) > 0 {
+		p.bindings = append(p.bindings, p.add(Item{Kind: KindBinding, Name: "ID policy", Details: details, apply: func(ctx context.Context) error {
+			_, err := idpolicy.ModifyPolicy(ctx, p.idPolicy, func(b []v1.IdPolicy) []v1.IdPolicy {
+				for j := range b {
+					b[j].Principals = slices.DeleteFunc(b[j].Principals, doomed)
+				}
+				return slices.DeleteFunc(b, func(x v1.IdPolicy) bool { return len(x.Principals) == 0 })
+			})
+			return err
+		}}))
+	}
+	return nil
+}
+
+// a project and, before it, its service principals with their keys and its
+// API keys
+func (p *planner) project(ctx context.Context, n *hierarchy.Node) error {
+	var needs []int
+	for _, k := range p.apiKeys {
+		if k.ProjectID == n.ID() {
+			needs = append(needs, p.add(Item{
+				Kind: KindAPIKey, Name: fmt.Sprintf("%s (#%d) of %s", k.Name, k.ID, n.Path()),
+				apply: func(ctx context.Context) error { return p.Deleter.apiKeys.Delete(ctx, k.ID) },
+			}))
+		}
+	}
+
+	for _, sp := range p.principals {
+		if sp.ProjectID != n.ID() {
+			continue
+		}
+		name := fmt.Sprintf("%s (#%d) of %s", sp.Name, sp.ID, n.Path())
+		keys, err := serviceprincipal.ListAllKeys(ctx, p.Deleter.principals, sp.ID, serviceprincipal.ListKeysParams{})
+		if err != nil {
+			return err
+		}
+
+		// A failed policy edit holds up every service principal, not only
+		// the ones it names: simpler, and no worse than leaving bindings to
+		// principals that no longer exist.
+		spNeeds := slices.Clone(p.bindings)
+		for _, k := range keys {
+			spNeeds = append(spNeeds, p.add(Item{
+				Kind: KindServicePrincipalKey, Name: fmt.Sprintf("%s of %s", k.Kid, name),
+				apply: func(ctx context.Context) error { return p.Deleter.principals.DeleteKey(ctx, sp.ID, k.ID) },
+			}))
+		}
+		needs = append(needs, p.add(Item{
+			Kind: KindServicePrincipal, Name: name, needs: spNeeds,
+			apply: func(ctx context.Context) error { return p.Deleter.principals.Delete(ctx, sp.ID) },
+		}))
+	}
+
+	details, err := p.policy(ctx, iampolicy.ProjectScope(n.ID()))
+	if err != nil {
+		return err
+	}
+	p.index[key(n.Kind, n.ID())] = p.add(Item{
+		Kind: KindProject, Name: n.Path(), Details: details, needs: needs,
+		apply: func(ctx context.Context) error { return p.projects.Delete(ctx, n.ID()) },
+	})
+	return nil
+}
+
+// a folder, after everything directly in it
+func (p *planner) folder(ctx context.Context, n *hierarchy.Node) error {
+	var needs []int
+	for _, c := range n.Children {
+		if i, ok := p.index[key(c.Kind, c.ID())]; ok {
+			needs = append(needs, i)
+		}
+	}
+	details, err := p.policy(ctx, iampolicy.FolderScope(n.ID()))
+	if err != nil {
+		return err
+	}
+	p.index[key(n.Kind, n.ID())] = p.add(Item{
+		Kind: KindFolder, Name: n.Path(), Details: details, needs: needs,
+		apply: func(ctx context.Context) error { return p.folders.Delete(ctx, n.ID()) },
+	})
+	return nil
+}
+
+// the bindings that go with a folder or project, for the report
+func (p *planner) policy(ctx context.Context, s iampolicy.Scope) ([]string, error) {
+	b, err := iampolicy.ReadPolicy(ctx, p.iamPolicy, s)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, x := range b {
+		for _, y := range x.Principals {
+			ret = append(ret, fmt.Sprintf("policy: %s: %s #%d", x.Role.Value.ID.Value, y.Type.Value, y.ID.Value))
+		}
+	}
+	return ret, nil
+}
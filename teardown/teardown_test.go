@@ -0,0 +1,170 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package teardown_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/projectapikey"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/hierarchy"
+	. "github.com/sacloud/iam-api-go/teardown"
+	iam_test "github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type env struct {
+	client       *v1.Client
+	root, sub    *v1.Folder
+	web, db      *v1.Project
+	deployer     *v1.ServicePrincipal
+	otherProject *v1.Project
+}
+
+// /env, /env/sub, /env/web (project), /env/sub/db (project), and /other
+// (project), which must survive.  The deployer service principal of web has
+// a key and bindings in the organization policy and the policy of /other, db
+// has an API key.
+func setup(t *testing.T) (*require.Assertions, *env) {
+	assert := require.New(t)
+	client, _ := iam_test.NewFakeClient(t)
+	ctx := t.Context()
+	e := env{client: client}
+	var err error
+
+	folders, projects := iam.NewFolderOp(client), iam.NewProjectOp(client)
+	e.root, err = folders.Create(ctx, folder.CreateParams{Name: "env"})
+	assert.NoError(err)
+	e.sub, err = folders.Create(ctx, folder.CreateParams{Name: "sub", ParentID: &e.root.ID})
+	assert.NoError(err)
+	e.web, err = projects.Create(ctx, project.CreateParams{Code: "web", Name: "web", ParentFolderID: &e.root.ID})
+	assert.NoError(err)
+	e.db, err = projects.Create(ctx, project.CreateParams{Code: "db", Name: "db", ParentFolderID: &e.sub.ID})
+	assert.NoError(err)
+	e.otherProject, err = projects.Create(ctx, project.CreateParams{Code: "other", Name: "other"})
+	assert.NoError(err)
+
+	sps := iam.NewServicePrincipalOp(client)
+	e.deployer, err = sps.Create(ctx, serviceprincipal.CreateParams{ProjectID: e.web.ID, Name: "deployer"})
+	assert.NoError(err)
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+	assert.NoError(err)
+	_, err = sps.UploadKey(ctx, e.deployer.ID, v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	assert.NoError(err)
+
+	_, err = iam.NewProjectAPIKeyOp(client).Create(ctx, projectapikey.CreateParams{ProjectID: e.db.ID, Name: "ci", IamRoles: []string{"admin"}})
+	assert.NoError(err)
+
+	_, err = iam.NewIAMPolicyOp(client).UpdateOrganizationPolicy(ctx, []v1.IamPolicy{
+		iam.IAMBinding("owner", iam.ServicePrincipalPrincipal(e.deployer.ID)),
+	})
+	assert.NoError(err)
+	_, err = iam.NewIAMPolicyOp(client).UpdateProjectPolicy(ctx, e.otherProject.ID, []v1.IamPolicy{
+		iam.IAMBinding("admin", iam.ServicePrincipalPrincipal(e.deployer.ID)),
+	})
+	assert.NoError(err)
+	return assert, &e
+}
+
+func TestPlan(t *testing.T) {
+	assert, e := setup(t)
+	d := NewDeleter(e.client)
+
+	plan, err := d.Plan(t.Context(), e.root.ID)
+	assert.NoError(err)
+	assert.Equal("/env", plan.Root.Path())
+	assert.Equal(2, plan.Count(KindFolder))
+	assert.Equal(2, plan.Count(KindProject))
+	assert.Equal(1, plan.Count(KindServicePrincipal))
+	assert.Equal(1, plan.Count(KindServicePrincipalKey))
+	assert.Equal(1, plan.Count(KindAPIKey))
+	assert.Equal(2, plan.Count(KindBinding))
+
+	s := plan.String()
+	assert.Contains(s, "- binding organization IAM policy\n    owner: service principal #")
+	assert.Contains(s, "- binding IAM policy of /other\n    admin: service principal #")
+	assert.Contains(s, "- api-key ci (#")
+	assert.Contains(s, "- folder /env/sub\n")
+	assert.Contains(s, "Delete /env: 2 folders, 2 projects, 1 service principals, 1 keys, 1 API keys, 2 policy edits.")
+
+	// nothing was touched
+	_, err = iam.NewFolderOp(e.client).Read(t.Context(), e.sub.ID)
+	assert.NoError(err)
+
+	_, err = d.Plan(t.Context(), 9999)
+	assert.Error(err)
+}
+
+func TestApply(t *testing.T) {
+	assert, e := setup(t)
+	ctx := t.Context()
+	d := NewDeleter(e.client)
+
+	plan, err := d.Plan(ctx, e.root.ID)
+	assert.NoError(err)
+	report, err := d.Apply(ctx, plan, Options{Concurrency: 4})
+	assert.NoError(err)
+	assert.Len(report.Deleted, len(plan.Items))
+	assert.Empty(report.Failed)
+	assert.Empty(report.Skipped)
+
+	tree, err := hierarchy.Fetch(ctx, iam.NewFolderOp(e.client), iam.NewProjectOp(e.client))
+	assert.NoError(err)
+	assert.Len(tree.Roots, 1)
+	assert.Equal("/other", tree.Roots[0].Path())
+
+	policy, err := iam.NewIAMPolicyOp(e.client).ReadOrganizationPolicy(ctx)
+	assert.NoError(err)
+	assert.Empty(policy)
+	policy, err = iam.NewIAMPolicyOp(e.client).ReadProjectPolicy(ctx, e.otherProject.ID)
+	assert.NoError(err)
+	assert.Empty(policy)
+	keys, err := projectapikey.ListAll(ctx, iam.NewProjectAPIKeyOp(e.client), projectapikey.ListParams{})
+	assert.NoError(err)
+	assert.Empty(keys)
+}
+
+func TestApply_PartialFailure(t *testing.T) {
+	assert, e := setup(t)
+	ctx := t.Context()
+	d := NewDeleter(e.client)
+
+	plan, err := d.Plan(ctx, e.root.ID)
+	assert.NoError(err)
+
+	// appears after planning, so /env/sub cannot be emptied and /env stays
+	_, err = iam.NewProjectOp(e.client).Create(ctx, project.CreateParams{Code: "late", Name: "late", ParentFolderID: &e.sub.ID})
+	assert.NoError(err)
+
+	report, err := d.Apply(ctx, plan, Options{Concurrency: 2})
+	assert.Error(err)
+	assert.ErrorContains(err, "folder /env/sub")
+	assert.Len(report.Failed, 1)
+	assert.Equal("folder /env/sub", report.Failed[0].Item)
+	assert.Equal([]string{"folder /env"}, report.Skipped)
+	assert.Contains(report.Deleted, "project /env/web")
+	assert.Contains(report.Deleted, "project /env/sub/db")
+	assert.Contains(report.String(), "7 deleted, 1 failed, 1 skipped.")
+}
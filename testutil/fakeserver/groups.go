@@ -0,0 +1,140 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// AddGroup creates a group without checking that its name is free, for
+// tests of callers that must cope with groups sharing a name.
+func (s *Server) AddGroup(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := now()
+	g := &v1.Group{ID: s.nextID(), Name: name, CreatedAt: t, UpdatedAt: t}
+	s.groups = append(s.groups, g)
+	return g.ID
+}
+
+func (s *Server) groupRoutes() {
+	s.handle("GET /groups", func(w http.ResponseWriter, r *http.Request) {
+		items := s.groups
+		if uid, ok := queryInt(r, "compat_user_id"); ok {
+			items = filter(items, func(g *v1.Group) bool { return slices.Contains(s.memberships[g.ID], uid) })
+		}
+		items = order(r, items, "name", func(g *v1.Group) string { return g.Name })
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /groups", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.GroupsPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if s.groupNameTaken(req.Name, 0) {
+			fail(w, http.StatusConflict, "group name %q is already taken", req.Name)
+			return
+		}
+
+		t := now()
+		g := &v1.Group{
+			ID:          s.nextID(),
+			Name:        req.Name,
+			Description: req.Description,
+			CreatedAt:   t,
+			UpdatedAt:   t,
+		}
+		s.groups = append(s.groups, g)
+		reply(w, http.StatusCreated, g)
+	})
+
+	s.handle("GET /groups/{group_id}", func(w http.ResponseWriter, r *http.Request) {
+		if g, _, ok := lookup(w, r, "group_id", s.groups); ok {
+			reply(w, http.StatusOK, g)
+		}
+	})
+	s.handle("PUT /groups/{group_id}", func(w http.ResponseWriter, r *http.Request) {
+		g, _, ok := lookup(w, r, "group_id", s.groups)
+		if !ok {
+			return
+		}
+		var req v1.GroupsGroupIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if s.groupNameTaken(req.Name, g.ID) {
+			invalid(w, "name", "group name %q is already taken", req.Name)
+			return
+		}
+		g.Name = req.Name
+		g.Description = req.Description
+		g.UpdatedAt = now()
+		reply(w, http.StatusOK, g)
+	})
+	s.handle("DELETE /groups/{group_id}", func(w http.ResponseWriter, r *http.Request) {
+		g, i, ok := lookup(w, r, "group_id", s.groups)
+		if !ok {
+			return
+		}
+		s.groups = slices.Delete(s.groups, i, i+1)
+		delete(s.memberships, g.ID)
+		s.forgetPrincipal("group", g.ID)
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("GET /groups/{group_id}/memberships", func(w http.ResponseWriter, r *http.Request) {
+		if g, _, ok := lookup(w, r, "group_id", s.groups); ok {
+			reply(w, http.StatusOK, s.membershipsOf(g.ID))
+		}
+	})
+	s.handle("PUT /groups/{group_id}/memberships", func(w http.ResponseWriter, r *http.Request) {
+		g, _, ok := lookup(w, r, "group_id", s.groups)
+		if !ok {
+			return
+		}
+		var req v1.GroupsGroupIDMembershipsPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+
+		var ids []int
+		for _, i := range req.CompatUsers {
+			if indexOf(s.users, i.ID) < 0 {
+				invalid(w, "compat_users", "user %d not found", i.ID)
+				return
+			}
+			if !slices.Contains(ids, i.ID) {
+				ids = append(ids, i.ID)
+			}
+		}
+		s.memberships[g.ID] = ids
+		reply(w, http.StatusOK, s.membershipsOf(g.ID))
+	})
+}
+
+func (s *Server) groupNameTaken(name string, except int) bool {
+	return slices.ContainsFunc(s.groups, func(g *v1.Group) bool { return g.ID != except && g.Name == name })
+}
+
+func (s *Server) membershipsOf(group int) *v1.GroupMemberships {
+	ret := &v1.GroupMemberships{CompatUsers: []v1.GroupMembershipsCompatUsersItem{}}
+	for _, i := range s.memberships[group] {
+		ret.CompatUsers = append(ret.CompatUsers, v1.GroupMembershipsCompatUsersItem{ID: i})
+	}
+	return ret
+}
@@ -0,0 +1,294 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// HideFolder leaves the folder id out of folder listings, as if the caller
+// were not allowed to see it.
+func (s *Server) HideFolder(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hiddenFolders[id] = true
+}
+
+func (s *Server) hierarchyRoutes() {
+	s.handle("GET /folders", func(w http.ResponseWriter, r *http.Request) {
+		items := filter(s.folders, func(f *v1.Folder) bool {
+			return !s.hiddenFolders[f.ID] && matches(r.URL.Query().Get("folder_name"), f.Name, true)
+		})
+		if p, ok := queryInt(r, "parent_id"); ok {
+			items = filter(items, func(f *v1.Folder) bool { return !f.ParentID.Null && f.ParentID.Value == p })
+		}
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /folders", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.FoldersPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		parent := v1.NilInt{Null: true}
+		if req.ParentID.Set && !req.ParentID.Null {
+			parent = v1.NewNilInt(req.ParentID.Value)
+		}
+		if !s.checkParent(w, parent) || !s.checkFolderName(w, req.Name, parent, 0) {
+			return
+		}
+
+		t := now()
+		f := &v1.Folder{
+			ID:          s.nextID(),
+			Name:        req.Name,
+			ParentID:    parent,
+			Description: req.Description.Value,
+			CreatedAt:   t,
+			UpdatedAt:   t,
+		}
+		s.folders = append(s.folders, f)
+		reply(w, http.StatusCreated, f)
+	})
+
+	s.handle("GET /folders/{folder_id}", func(w http.ResponseWriter, r *http.Request) {
+		if f, _, ok := lookup(w, r, "folder_id", s.folders); ok {
+			reply(w, http.StatusOK, f)
+		}
+	})
+	s.handle("PUT /folders/{folder_id}", func(w http.ResponseWriter, r *http.Request) {
+		f, _, ok := lookup(w, r, "folder_id", s.folders)
+		if !ok {
+			return
+		}
+		var req v1.FoldersFolderIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.checkFolderName(w, req.Name, f.ParentID, f.ID) {
+			return
+		}
+		f.Name = req.Name
+		if req.Description.Set {
+			f.Description = req.Description.Value
+		}
+		f.UpdatedAt = now()
+		reply(w, http.StatusOK, f)
+	})
+	s.handle("DELETE /folders/{folder_id}", func(w http.ResponseWriter, r *http.Request) {
+		f, i, ok := lookup(w, r, "folder_id", s.folders)
+		if !ok {
+			return
+		}
+		if s.hasChildren(f.ID) {
+			reject(w, "exist_dependency_folders_or_projects", "下の階層にフォルダまたはプロジェクトがあります。")
+			return
+		}
+		s.folders = slices.Delete(s.folders, i, i+1)
+		delete(s.iamPolicies, scope{"folder", f.ID})
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("POST /move-folders", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.MoveFolders
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.checkParent(w, req.ParentID) {
+			return
+		}
+		for _, id := range req.FolderIds {
+			i := indexOf(s.folders, id)
+			if i < 0 {
+				reject(w, "folder_not_found", "指定されたフォルダが見つかりません")
+				return
+			}
+			if !req.ParentID.Null && s.isAncestor(id, req.ParentID.Value) {
+				reject(w, "invalid_parent", "フォルダを自身の配下に移動できません。")
+				return
+			}
+			if !s.checkFolderName(w, s.folders[i].Name, req.ParentID, id) {
+				return
+			}
+		}
+		for _, id := range req.FolderIds {
+			f := s.folders[indexOf(s.folders, id)]
+			f.ParentID = req.ParentID
+			f.UpdatedAt = now()
+		}
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("GET /projects", func(w http.ResponseWriter, r *http.Request) {
+		items := s.projects
+		if p, ok := queryInt(r, "parent_folder_id"); ok {
+			items = filter(items, func(i *v1.Project) bool { return !i.ParentFolderID.Null && i.ParentFolderID.Value == p })
+		}
+		items = order(r, items, "code", func(i *v1.Project) string { return i.Code })
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /projects", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.ProjectsPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		parent := v1.NilInt{Null: true}
+		if req.ParentFolderID.Set {
+			parent = v1.NewNilInt(req.ParentFolderID.Value)
+		}
+		if slices.ContainsFunc(s.projects, func(p *v1.Project) bool { return p.Code == req.Code }) {
+			fail(w, http.StatusConflict, "project code %q is already taken", req.Code)
+			return
+		}
+		if !s.checkParent(w, parent) || !s.checkProjectName(w, req.Name, parent, 0) {
+			return
+		}
+
+		t := now()
+		p := &v1.Project{
+			ID:             s.nextID(),
+			Code:           req.Code,
+			Name:           req.Name,
+			Description:    req.Description,
+			Status:         v1.ProjectStatusAvailable,
+			ParentFolderID: parent,
+			CreatedAt:      t,
+			UpdatedAt:      t,
+		}
+		s.projects = append(s.projects, p)
+		reply(w, http.StatusCreated, p)
+	})
+
+	s.handle("GET /projects/{project_id}", func(w http.ResponseWriter, r *http.Request) {
+		if p, _, ok := lookup(w, r, "project_id", s.projects); ok {
+			reply(w, http.StatusOK, p)
+		}
+	})
+	s.handle("PUT /projects/{project_id}", func(w http.ResponseWriter, r *http.Request) {
+		p, _, ok := lookup(w, r, "project_id", s.projects)
+		if !ok {
+			return
+		}
+		var req v1.ProjectsProjectIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.checkProjectName(w, req.Name, p.ParentFolderID, p.ID) {
+			return
+		}
+		p.Name = req.Name
+		p.Description = req.Description
+		p.UpdatedAt = now()
+		reply(w, http.StatusOK, p)
+	})
+	s.handle("DELETE /projects/{project_id}", func(w http.ResponseWriter, r *http.Request) {
+		p, i, ok := lookup(w, r, "project_id", s.projects)
+		if !ok {
+			return
+		}
+		if slices.ContainsFunc(s.servicePrincipals, func(sp *v1.ServicePrincipal) bool { return sp.ProjectID == p.ID }) {
+			fail(w, http.StatusConflict, "project %d still has service principals", p.ID)
+			return
+		}
+		if slices.ContainsFunc(s.apiKeys, func(k *v1.ProjectApiKeyWithSecret) bool { return k.ProjectID == p.ID }) {
+			fail(w, http.StatusConflict, "project %d still has API keys", p.ID)
+			return
+		}
+		s.projects = slices.Delete(s.projects, i, i+1)
+		delete(s.iamPolicies, scope{"project", p.ID})
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("POST /move-projects", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.MoveProjects
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.checkParent(w, req.ParentFolderID) {
+			return
+		}
+		for _, id := range req.ProjectIds {
+			i := indexOf(s.projects, id)
+			if i < 0 {
+				reject(w, "project_not_found", "指定されたプロジェクトが見つかりません。")
+				return
+			}
+			if !s.checkProjectName(w, s.projects[i].Name, req.ParentFolderID, id) {
+				return
+			}
+		}
+		for _, id := range req.ProjectIds {
+			p := s.projects[indexOf(s.projects, id)]
+			p.ParentFolderID = req.ParentFolderID
+			p.UpdatedAt = now()
+		}
+		reply(w, http.StatusNoContent, nil)
+	})
+}
+
+func (s *Server) checkParent(w http.ResponseWriter, parent v1.NilInt) bool {
+	if !parent.Null && indexOf(s.folders, parent.Value) < 0 {
+		reject(w, "parant_not_found", "親フォルダが見つかりません。")
+		return false
+	}
+	return true
+}
+
+func (s *Server) checkFolderName(w http.ResponseWriter, name string, parent v1.NilInt, self int) bool {
+	if slices.ContainsFunc(s.folders, func(f *v1.Folder) bool {
+		return f.ID != self && f.Name == name && sameParent(f.ParentID, parent)
+	}) {
+		reject(w, "duplicate_folder_names", "同じ階層に同じ名前のフォルダを登録できません。")
+		return false
+	}
+	return true
+}
+
+func (s *Server) checkProjectName(w http.ResponseWriter, name string, parent v1.NilInt, self int) bool {
+	if slices.ContainsFunc(s.projects, func(p *v1.Project) bool {
+		return p.ID != self && p.Name == name && sameParent(p.ParentFolderID, parent)
+	}) {
+		reject(w, "duplicate_project_names", "同じ階層に同じ名前のプロジェクトを登録できません。")
+		return false
+	}
+	return true
+}
+
+func (s *Server) hasChildren(folder int) bool {
+	parent := v1.NewNilInt(folder)
+	return slices.ContainsFunc(s.folders, func(f *v1.Folder) bool { return sameParent(f.ParentID, parent) }) ||
+		slices.ContainsFunc(s.projects, func(p *v1.Project) bool { return sameParent(p.ParentFolderID, parent) })
+}
+
+func sameParent(a, b v1.NilInt) bool {
+	return a.Null == b.Null && (a.Null || a.Value == b.Value)
+}
+
+// isAncestor reports whether folder is descendant itself or one of its
+// ancestors.
+func (s *Server) isAncestor(folder, descendant int) bool {
+	for id := descendant; ; {
+		if id == folder {
+			return true
+		}
+		i := indexOf(s.folders, id)
+		if i < 0 || s.folders[i].ParentID.Null {
+			return false
+		}
+		id = s.folders[i].ParentID.Value
+	}
+}
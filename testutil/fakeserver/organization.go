@@ -0,0 +1,203 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// These are made up; the real catalogue is much longer.
+var presetRuleTemplates = []v1.RuleTemplate{
+	{
+		Code:           v1.NewOptString("allowed-zones"),
+		Name:           v1.NewOptString("利用可能なゾーン"),
+		Description:    v1.NewOptString("リソースを作成できるゾーンを制限します"),
+		Type:           v1.NewOptString(string(v1.OrganizationServicePolicyGetTypeList)),
+		SupportsDryRun: v1.NewOptBool(true),
+		Prefixes:       []string{},
+	},
+	{
+		Code:           v1.NewOptString("deny-shared-segment"),
+		Name:           v1.NewOptString("共有セグメントの禁止"),
+		Description:    v1.NewOptString("共有セグメントへの接続を禁止します"),
+		Type:           v1.NewOptString(string(v1.OrganizationServicePolicyGetTypeBool)),
+		SupportsDryRun: v1.NewOptBool(false),
+		Prefixes:       []string{},
+	},
+}
+
+// AddRuleTemplate makes another service policy rule template available.
+func (s *Server) AddRuleTemplate(t v1.RuleTemplate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ruleTemplates = append(s.ruleTemplates, &t)
+}
+
+// SetAuthContext changes what GET /auth/context reports about the caller.
+func (s *Server) SetAuthContext(c v1.GetAuthContextOK) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authContext = c
+}
+
+func (s *Server) organizationRoutes() {
+	s.handle("GET /auth/context", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &s.authContext)
+	})
+
+	s.handle("GET /organization", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &s.organization)
+	})
+	s.handle("PUT /organization", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.OrganizationPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		s.organization.Name = req.Name
+		reply(w, http.StatusOK, &s.organization)
+	})
+
+	s.handle("GET /organization-password-policy", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &s.passwordPolicy)
+	})
+	s.handle("PUT /organization-password-policy", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.PasswordPolicy
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.MinLength < 8 {
+			invalid(w, "min_length", "must be at least 8")
+			return
+		}
+		s.passwordPolicy = req
+		reply(w, http.StatusOK, &s.passwordPolicy)
+	})
+
+	s.handle("GET /organization-auth-conditions", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &s.authConditions)
+	})
+	s.handle("PUT /organization-auth-conditions", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.AuthConditions
+		if !decode(w, r, &req) {
+			return
+		}
+		s.authConditions = req
+		reply(w, http.StatusOK, &s.authConditions)
+	})
+
+	s.handle("GET /service-policy-status", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &v1.ServicePolicyStatusGetOK{Enabled: s.servicePolicyEnabled})
+	})
+	s.handle("POST /enable-service-policy", func(w http.ResponseWriter, r *http.Request) {
+		if s.servicePolicyEnabled {
+			fail(w, http.StatusConflict, "service policy is already enabled")
+			return
+		}
+		s.servicePolicyEnabled = true
+		reply(w, http.StatusNoContent, nil)
+	})
+	s.handle("POST /disable-service-policy", func(w http.ResponseWriter, r *http.Request) {
+		if !s.servicePolicyEnabled {
+			fail(w, http.StatusConflict, "service policy is already disabled")
+			return
+		}
+		s.servicePolicyEnabled = false
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("GET /service-policy-rule-templates", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		items := filter(s.ruleTemplates, func(t *v1.RuleTemplate) bool {
+			return matches(q.Get("name"), t.Name.Value, true) &&
+				matches(q.Get("code"), t.Code.Value, false) &&
+				matches(q.Get("type"), t.Type.Value, false)
+		})
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+
+	s.handle("GET /organization-service-policy", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		rules := filter(s.servicePolicy, func(i v1.RuleResponse) bool {
+			var typ string
+			if t := s.ruleTemplate(i.Code.Value); t != nil {
+				typ = t.Type.Value
+			}
+			return matches(q.Get("name"), i.Name.Value, true) &&
+				matches(q.Get("code"), i.Code.Value, false) &&
+				matches(q.Get("type"), typ, false) &&
+				matches(q.Get("is_active"), strconv.FormatBool(i.IsActive.Value), false) &&
+				matches(q.Get("is_dry_run"), strconv.FormatBool(i.IsDryRun.Value), false)
+		})
+		reply(w, http.StatusOK, &v1.OrganizationServicePolicyGetOK{Rules: rules})
+	})
+	s.handle("PUT /organization-service-policy", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.OrganizationServicePolicyPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.servicePolicyEnabled {
+			fail(w, http.StatusConflict, "service policy is disabled")
+			return
+		}
+
+		rules := make([]v1.RuleResponse, 0, len(req.Rules))
+		for _, i := range req.Rules {
+			t := s.ruleTemplate(i.Code.Value)
+			if t == nil {
+				invalid(w, "rules", "unknown rule template %q", i.Code.Value)
+				return
+			}
+			if i.IsDryRun.Value && !t.SupportsDryRun.Value {
+				invalid(w, "rules", "rule %q does not support dry run", i.Code.Value)
+				return
+			}
+			rules = append(rules, v1.RuleResponse{
+				Code:       i.Code,
+				Name:       t.Name,
+				Spec:       i.Spec,
+				DryRunSpec: i.DryRunSpec,
+				IsActive:   i.IsActive,
+				IsDryRun:   i.IsDryRun,
+			})
+		}
+		s.servicePolicy = rules
+		reply(w, http.StatusOK, &v1.OrganizationServicePolicyPutOK{Rules: rules})
+	})
+}
+
+func (s *Server) ruleTemplate(code string) *v1.RuleTemplate {
+	if i := slices.IndexFunc(s.ruleTemplates, func(t *v1.RuleTemplate) bool { return t.Code.Value == code }); i >= 0 {
+		return s.ruleTemplates[i]
+	}
+	return nil
+}
+
+// matches implements an optional query filter: an empty want matches
+// anything, otherwise got must equal (or contain, if partial) it.
+func matches(want, got string, partial bool) bool {
+	switch {
+	case want == "":
+		return true
+	case partial:
+		return strings.Contains(got, want)
+	default:
+		return want == got
+	}
+}
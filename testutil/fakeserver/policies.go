@@ -0,0 +1,215 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// Those listed in doc/testing.md, plus a project level one.
+var presetIAMRoles = []v1.IamRole{
+	{ID: "owner", Name: "オーナー", Category: "organization", LowestGrantableResource: v1.IamRoleLowestGrantableResourceOrganization},
+	{ID: "organization-admin", Name: "組織管理者", Category: "organization", LowestGrantableResource: v1.IamRoleLowestGrantableResourceOrganization},
+	{ID: "servicepolicy-admin", Name: "サービスポリシー管理者", Category: "organization", LowestGrantableResource: v1.IamRoleLowestGrantableResourceOrganization},
+	{ID: "project-creator", Name: "プロジェクト作成者", Category: "organization", LowestGrantableResource: v1.IamRoleLowestGrantableResourceOrganization},
+	{ID: "folder-admin", Name: "フォルダ管理者", Category: "folder", LowestGrantableResource: v1.IamRoleLowestGrantableResourceFolder},
+	{ID: "admin", Name: "管理者", Category: "project", LowestGrantableResource: v1.IamRoleLowestGrantableResourceProject},
+}
+
+var presetIDRoles = []v1.IdRole{
+	{ID: "identity-admin", Name: "ID管理者"},
+}
+
+// AddIAMRole makes another preset IAM role available.
+func (s *Server) AddIAMRole(role v1.IamRole) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.iamRoles = append(s.iamRoles, &role)
+}
+
+// AddIDRole makes another preset ID role available.
+func (s *Server) AddIDRole(role v1.IdRole) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idRoles = append(s.idRoles, &role)
+}
+
+// How deep in the hierarchy each kind of scope is.
+var depth = map[string]int{
+	string(v1.IamRoleLowestGrantableResourceOrganization): 0,
+	string(v1.IamRoleLowestGrantableResourceFolder):       1,
+	string(v1.IamRoleLowestGrantableResourceProject):      2,
+}
+
+func (s *Server) policyRoutes() {
+	s.handle("GET /iam-roles", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, paginate(r, s.iamRoles))
+	})
+	s.handle("GET /iam-roles/{iam_role_id}", func(w http.ResponseWriter, r *http.Request) {
+		if i := s.iamRole(r.PathValue("iam_role_id")); i != nil {
+			reply(w, http.StatusOK, i)
+		} else {
+			fail(w, http.StatusNotFound, "iam role %q not found", r.PathValue("iam_role_id"))
+		}
+	})
+	s.handle("GET /id-roles", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, paginate(r, s.idRoles))
+	})
+	s.handle("GET /id-roles/{id_role_id}", func(w http.ResponseWriter, r *http.Request) {
+		if i := s.idRole(r.PathValue("id_role_id")); i != nil {
+			reply(w, http.StatusOK, i)
+		} else {
+			fail(w, http.StatusNotFound, "id role %q not found", r.PathValue("id_role_id"))
+		}
+	})
+
+	s.handle("GET /organization-iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		s.getIAMPolicy(w, organizationScope)
+	})
+	s.handle("PUT /organization-iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		s.putIAMPolicy(w, r, organizationScope)
+	})
+	s.handle("GET /folders/{folder_id}/iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		if f, _, ok := lookup(w, r, "folder_id", s.folders); ok {
+			s.getIAMPolicy(w, scope{"folder", f.ID})
+		}
+	})
+	s.handle("PUT /folders/{folder_id}/iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		if f, _, ok := lookup(w, r, "folder_id", s.folders); ok {
+			s.putIAMPolicy(w, r, scope{"folder", f.ID})
+		}
+	})
+	s.handle("GET /projects/{project_id}/iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		if p, _, ok := lookup(w, r, "project_id", s.projects); ok {
+			s.getIAMPolicy(w, scope{"project", p.ID})
+		}
+	})
+	s.handle("PUT /projects/{project_id}/iam-policy", func(w http.ResponseWriter, r *http.Request) {
+		if p, _, ok := lookup(w, r, "project_id", s.projects); ok {
+			s.putIAMPolicy(w, r, scope{"project", p.ID})
+		}
+	})
+
+	s.handle("GET /organization-id-policy", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, &v1.OrganizationIDPolicyGetOK{Bindings: append([]v1.IdPolicy{}, s.idPolicy...)})
+	})
+	s.handle("PUT /organization-id-policy", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.OrganizationIDPolicyPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		for _, b := range req.Bindings {
+			if s.idRole(b.Role.Value.ID.Value) == nil {
+				invalid(w, "bindings", "id role %q not found", b.Role.Value.ID.Value)
+				return
+			}
+			if !s.checkPrincipals(w, b.Principals) {
+				return
+			}
+		}
+		s.idPolicy = req.Bindings
+		reply(w, http.StatusOK, &v1.OrganizationIDPolicyPutOK{Bindings: append([]v1.IdPolicy{}, s.idPolicy...)})
+	})
+}
+
+func (s *Server) iamRole(id string) *v1.IamRole {
+	if i := slices.IndexFunc(s.iamRoles, func(r *v1.IamRole) bool { return r.ID == id }); i >= 0 {
+		return s.iamRoles[i]
+	}
+	return nil
+}
+
+func (s *Server) idRole(id string) *v1.IdRole {
+	if i := slices.IndexFunc(s.idRoles, func(r *v1.IdRole) bool { return r.ID == id }); i >= 0 {
+		return s.idRoles[i]
+	}
+	return nil
+}
+
+func (s *Server) getIAMPolicy(w http.ResponseWriter, at scope) {
+	reply(w, http.StatusOK, &v1.OrganizationIamPolicyGetOK{Bindings: append([]v1.IamPolicy{}, s.iamPolicies[at]...)})
+}
+
+func (s *Server) putIAMPolicy(w http.ResponseWriter, r *http.Request, at scope) {
+	var req v1.OrganizationIamPolicyPutReq
+	if !decode(w, r, &req) {
+		return
+	}
+	for _, b := range req.Bindings {
+		role := s.iamRole(b.Role.Value.ID.Value)
+		if role == nil {
+			invalid(w, "bindings", "iam role %q not found", b.Role.Value.ID.Value)
+			return
+		}
+		if depth[string(role.LowestGrantableResource)] < depth[at.kind] {
+			invalid(w, "bindings", "iam role %q cannot be granted on a %s", role.ID, at.kind)
+			return
+		}
+		if !s.checkPrincipals(w, b.Principals) {
+			return
+		}
+	}
+	s.iamPolicies[at] = req.Bindings
+	reply(w, http.StatusOK, &v1.OrganizationIamPolicyPutOK{Bindings: append([]v1.IamPolicy{}, req.Bindings...)})
+}
+
+func (s *Server) checkPrincipals(w http.ResponseWriter, principals []v1.Principal) bool {
+	for _, p := range principals {
+		var found bool
+		switch p.Type.Value {
+		case "user":
+			found = indexOf(s.users, p.ID.Value) >= 0
+		case "group":
+			found = indexOf(s.groups, p.ID.Value) >= 0
+		case "service-principal":
+			found = indexOf(s.servicePrincipals, p.ID.Value) >= 0
+		default:
+			invalid(w, "bindings", "unknown principal type %q", p.Type.Value)
+			return false
+		}
+		if !found {
+			invalid(w, "bindings", "%s %d not found", p.Type.Value, p.ID.Value)
+			return false
+		}
+	}
+	return true
+}
+
+// forgetPrincipal drops a deleted principal from every policy, along with
+// the bindings it leaves empty.
+func (s *Server) forgetPrincipal(kind string, id int) {
+	gone := func(p v1.Principal) bool { return p.Type.Value == kind && p.ID.Value == id }
+
+	for at, bindings := range s.iamPolicies {
+		var kept []v1.IamPolicy
+		for _, b := range bindings {
+			if b.Principals = slices.DeleteFunc(b.Principals, gone); len(b.Principals) > 0 {
+				kept = append(kept, b)
+			}
+		}
+		s.iamPolicies[at] = kept
+	}
+
+	var kept []v1.IdPolicy
+	for _, b := range s.idPolicy {
+		if b.Principals = slices.DeleteFunc(b.Principals, gone); len(b.Principals) > 0 {
+			kept = append(kept, b)
+		}
+	}
+	s.idPolicy = kept
+}
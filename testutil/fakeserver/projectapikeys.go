@@ -0,0 +1,128 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+func (s *Server) projectAPIKeyRoutes() {
+	s.handle("GET /compat/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		items := order(r, s.apiKeys, "name", func(k *v1.ProjectApiKeyWithSecret) string { return k.Name })
+		ret := make([]*v1.ProjectApiKey, 0, len(items))
+		for _, i := range items {
+			ret = append(ret, withoutSecret(i))
+		}
+		reply(w, http.StatusOK, paginate(r, ret))
+	})
+	s.handle("POST /compat/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.CompatAPIKeysPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if indexOf(s.projects, req.ProjectID) < 0 {
+			invalid(w, "project_id", "project %d not found", req.ProjectID)
+			return
+		}
+		if !s.checkIAMRoles(w, req.IamRoles) {
+			return
+		}
+
+		t := now()
+		k := &v1.ProjectApiKeyWithSecret{
+			ID:                s.nextID(),
+			ProjectID:         req.ProjectID,
+			Name:              req.Name,
+			Description:       req.Description,
+			AccessToken:       uuid.NewString(),
+			ServerResourceID:  optNil(req.ServerResourceID),
+			IamRoles:          slices.Clone(req.IamRoles),
+			ZoneID:            optNil(req.ZoneID),
+			CreatedAt:         v1.NewOptString(t),
+			UpdatedAt:         v1.NewOptString(t),
+			AccessTokenSecret: randomHex(32),
+		}
+		s.apiKeys = append(s.apiKeys, k)
+		reply(w, http.StatusCreated, k)
+	})
+
+	s.handle("GET /compat/api-keys/{apikey_id}", func(w http.ResponseWriter, r *http.Request) {
+		if k, _, ok := lookup(w, r, "apikey_id", s.apiKeys); ok {
+			reply(w, http.StatusOK, withoutSecret(k))
+		}
+	})
+	s.handle("PUT /compat/api-keys/{apikey_id}", func(w http.ResponseWriter, r *http.Request) {
+		k, _, ok := lookup(w, r, "apikey_id", s.apiKeys)
+		if !ok {
+			return
+		}
+		var req v1.CompatAPIKeysApikeyIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if !s.checkIAMRoles(w, req.IamRoles) {
+			return
+		}
+		k.Name = req.Name
+		k.Description = req.Description
+		k.ServerResourceID = optNil(req.ServerResourceID)
+		k.IamRoles = slices.Clone(req.IamRoles)
+		k.ZoneID = optNil(req.ZoneID)
+		k.UpdatedAt = v1.NewOptString(now())
+		reply(w, http.StatusOK, withoutSecret(k))
+	})
+	s.handle("DELETE /compat/api-keys/{apikey_id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, i, ok := lookup(w, r, "apikey_id", s.apiKeys); ok {
+			s.apiKeys = slices.Delete(s.apiKeys, i, i+1)
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+}
+
+func (s *Server) checkIAMRoles(w http.ResponseWriter, roles []string) bool {
+	for _, i := range roles {
+		if s.iamRole(i) == nil {
+			invalid(w, "iam_roles", "iam role %q not found", i)
+			return false
+		}
+	}
+	return true
+}
+
+func withoutSecret(k *v1.ProjectApiKeyWithSecret) *v1.ProjectApiKey {
+	return &v1.ProjectApiKey{
+		ID:               k.ID,
+		ProjectID:        k.ProjectID,
+		Name:             k.Name,
+		Description:      k.Description,
+		AccessToken:      k.AccessToken,
+		ServerResourceID: k.ServerResourceID,
+		IamRoles:         k.IamRoles,
+		ZoneID:           k.ZoneID,
+		CreatedAt:        k.CreatedAt,
+		UpdatedAt:        k.UpdatedAt,
+	}
+}
+
+func optNil(v v1.OptString) v1.OptNilString {
+	if v.Set {
+		return v1.NewOptNilString(v.Value)
+	}
+	return v1.OptNilString{Set: true, Null: true}
+}
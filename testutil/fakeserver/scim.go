@@ -0,0 +1,114 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/google/uuid"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+func (s *Server) scimRoutes() {
+	s.handle("GET /scim-configurations", func(w http.ResponseWriter, r *http.Request) {
+		items := make([]*v1.ScimConfigurationBase, 0, len(s.scim))
+		for _, i := range s.scim {
+			items = append(items, withoutToken(i))
+		}
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /scim-configurations", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.ScimConfigurationsPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if len(s.scim) > 0 {
+			fail(w, http.StatusConflict, "a SCIM configuration already exists")
+			return
+		}
+
+		base, err := url.Parse(s.URL)
+		if err != nil {
+			fail(w, http.StatusInternalServerError, "%s", err)
+			return
+		}
+		t := now()
+		c := &v1.ScimConfiguration{
+			ID:          uuid.New(),
+			Name:        req.Name,
+			CreatedAt:   t,
+			UpdatedAt:   t,
+			SecretToken: randomHex(32),
+		}
+		c.BaseURL = *base.JoinPath("scim", "v2", c.ID.String())
+		s.scim = append(s.scim, c)
+		reply(w, http.StatusCreated, c)
+	})
+
+	s.handle("GET /scim-configurations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if c, _, ok := s.lookupScim(w, r); ok {
+			reply(w, http.StatusOK, withoutToken(c))
+		}
+	})
+	s.handle("PUT /scim-configurations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		c, _, ok := s.lookupScim(w, r)
+		if !ok {
+			return
+		}
+		var req v1.ScimConfigurationsIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		c.Name = req.Name
+		c.UpdatedAt = now()
+		reply(w, http.StatusOK, withoutToken(c))
+	})
+	s.handle("DELETE /scim-configurations/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, i, ok := s.lookupScim(w, r); ok {
+			s.scim = slices.Delete(s.scim, i, i+1)
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+	s.handle("POST /scim-configurations/{id}/regenerate-token", func(w http.ResponseWriter, r *http.Request) {
+		if c, _, ok := s.lookupScim(w, r); ok {
+			c.SecretToken = randomHex(32)
+			c.UpdatedAt = now()
+			reply(w, http.StatusOK, &v1.ScimConfigurationsIDRegenerateTokenPostOK{SecretToken: v1.NewOptString(c.SecretToken)})
+		}
+	})
+}
+
+func (s *Server) lookupScim(w http.ResponseWriter, r *http.Request) (*v1.ScimConfiguration, int, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err == nil {
+		if i := slices.IndexFunc(s.scim, func(c *v1.ScimConfiguration) bool { return c.ID == id }); i >= 0 {
+			return s.scim[i], i, true
+		}
+	}
+	fail(w, http.StatusNotFound, "SCIM configuration %q not found", r.PathValue("id"))
+	return nil, -1, false
+}
+
+func withoutToken(c *v1.ScimConfiguration) *v1.ScimConfigurationBase {
+	return &v1.ScimConfigurationBase{
+		ID:        c.ID,
+		Name:      c.Name,
+		BaseURL:   c.BaseURL,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeserver is a stateful, in-memory imitation of the IAM API.
+//
+// It implements every endpoint of openapi/openapi.json on top of an
+// httptest.Server, remembering what has been created so that multi-step flows
+// (create a project, bind a policy to it, read it back ...) can be tested
+// without TESTACC credentials.  Errors follow the real service closely enough
+// to exercise error handling: unknown IDs are 404, duplicates and dependent
+// resources are 409, invalid references are 400.
+//
+// It is not an authorisation server: every request is accepted regardless
+// of its credentials.
+package fakeserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/common"
+)
+
+// DefaultPerPage is the page size used when a List request omits per_page.
+const DefaultPerPage = 20
+
+// Server is the fake.  Create one with New and Close it when done.
+type Server struct {
+	*httptest.Server
+
+	// KeysPerServicePrincipal caps how many keys a service principal can
+	// hold; uploading one more is a 409.
+	KeysPerServicePrincipal int
+
+	mu  sync.Mutex
+	mux *http.ServeMux
+	seq int
+
+	organization   v1.Organization
+	passwordPolicy v1.PasswordPolicy
+	authConditions v1.AuthConditions
+	authContext    v1.GetAuthContextOK
+
+	servicePolicyEnabled bool
+	servicePolicy        []v1.RuleResponse
+	ruleTemplates        []*v1.RuleTemplate
+
+	iamRoles []*v1.IamRole
+	idRoles  []*v1.IdRole
+
+	users       []*user
+	groups      []*v1.Group
+	memberships map[int][]int
+
+	folders       []*v1.Folder
+	projects      []*v1.Project
+	hiddenFolders map[int]bool
+
+	iamPolicies map[scope][]v1.IamPolicy
+	idPolicy    []v1.IdPolicy
+
+	servicePrincipals []*v1.ServicePrincipal
+	keys              map[int][]*v1.ServicePrincipalKey
+	apiKeys           []*v1.ProjectApiKeyWithSecret
+
+	ssoProfiles []*v1.SSOProfile
+	scim        []*v1.ScimConfiguration
+}
+
+// scope identifies the resource an IAM policy is attached to.
+type scope struct {
+	kind string // "organization", "folder" or "project"
+	id   int
+}
+
+var organizationScope = scope{kind: "organization"}
+
+// New starts a fake seeded with an organization, the preset IAM and ID roles
+// and a handful of service policy rule templates.
+func New() *Server {
+	s := &Server{
+		KeysPerServicePrincipal: 2,
+
+		mux: http.NewServeMux(),
+		seq: 100000,
+
+		organization: v1.Organization{ID: v1.NewOptInt(100000), Name: "fake organization"},
+		passwordPolicy: v1.PasswordPolicy{
+			MinLength:        8,
+			RequireUppercase: false,
+			RequireLowercase: false,
+			RequireSymbols:   false,
+		},
+		authContext: v1.GetAuthContextOK{
+			ResourceID:         100000,
+			AuthType:           v1.GetAuthContextOKAuthTypeApikey,
+			LimitedToProjectID: v1.NilInt{Null: true},
+		},
+
+		memberships:   map[int][]int{},
+		hiddenFolders: map[int]bool{},
+		iamPolicies:   map[scope][]v1.IamPolicy{},
+		keys:          map[int][]*v1.ServicePrincipalKey{},
+	}
+
+	mustDecode(&s.authConditions, `{
+		"ip_restriction": {"mode": "allow_all"},
+		"require_two_factor_auth": {"enabled": false},
+		"datetime_restriction": {"after": null, "before": null}
+	}`)
+
+	for _, i := range presetIAMRoles {
+		s.AddIAMRole(i)
+	}
+	for _, i := range presetIDRoles {
+		s.AddIDRole(i)
+	}
+	for _, i := range presetRuleTemplates {
+		s.AddRuleTemplate(i)
+	}
+
+	s.routes()
+	s.Server = httptest.NewServer(s.mux)
+	return s
+}
+
+func (s *Server) routes() {
+	s.organizationRoutes()
+	s.userRoutes()
+	s.groupRoutes()
+	s.hierarchyRoutes()
+	s.policyRoutes()
+	s.servicePrincipalRoutes()
+	s.projectAPIKeyRoutes()
+	s.ssoRoutes()
+	s.scimRoutes()
+}
+
+// handle registers h under a Go 1.22 ServeMux pattern.  Handlers run one at
+// a time, so they can freely touch the state.
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(w, r)
+	})
+}
+
+func (s *Server) nextID() int {
+	s.seq++
+	return s.seq
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func mustDecode(v json.Unmarshaler, j string) {
+	if err := v.UnmarshalJSON([]byte(j)); err != nil {
+		panic(err)
+	}
+}
+
+// decode reads the request body into v, answering 400 on failure.
+func decode(w http.ResponseWriter, r *http.Request, v json.Unmarshaler) bool {
+	b, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = v.UnmarshalJSON(b)
+	}
+	if err != nil {
+		invalid(w, common.NonFieldErrors, "%s", err)
+		return false
+	}
+	return true
+}
+
+func reply(w http.ResponseWriter, status int, v any) {
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(j)
+}
+
+// fail answers with an RFC 7807 problem body.
+func fail(w http.ResponseWriter, status int, format string, args ...any) {
+	reply(w, status, &v1.Http404NotFound{
+		Type:   "about:blank",
+		Status: status,
+		Title:  http.StatusText(status),
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+// invalid answers 400 with a single field error.
+func invalid(w http.ResponseWriter, field string, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	var errs v1.Http400BadRequestErrors
+	if field == common.NonFieldErrors {
+		errs.NonFieldErrors = []v1.Http400BadRequestErrorsNonFieldErrorsItem{{Message: msg, Code: "invalid"}}
+	} else {
+		errs.AdditionalProps = v1.Http400BadRequestErrorsAdditional{
+			field: {{Message: msg, Code: "invalid"}},
+		}
+	}
+	reply(w, http.StatusBadRequest, &v1.Http400BadRequest{
+		Type:   "about:blank",
+		Status: http.StatusBadRequest,
+		Title:  http.StatusText(http.StatusBadRequest),
+		Detail: msg,
+		Errors: errs,
+	})
+}
+
+// reject answers 400 the way the hierarchy endpoints do: a machine readable
+// title and no field errors.
+func reject(w http.ResponseWriter, title string, format string, args ...any) {
+	reply(w, http.StatusBadRequest, &v1.Http400BadRequest{
+		Type:   "about:blank",
+		Status: http.StatusBadRequest,
+		Title:  title,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+type identified interface{ GetID() int }
+
+func indexOf[P identified](items []P, id int) int {
+	return slices.IndexFunc(items, func(i P) bool { return i.GetID() == id })
+}
+
+// lookup resolves the integer path parameter `name` against items, answering
+// 404 if there is no such thing.
+func lookup[P identified](w http.ResponseWriter, r *http.Request, name string, items []P) (P, int, bool) {
+	var zero P
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		fail(w, http.StatusNotFound, "%s %q not found", name, r.PathValue(name))
+		return zero, -1, false
+	}
+	i := indexOf(items, id)
+	if i < 0 {
+		fail(w, http.StatusNotFound, "%s %d not found", name, id)
+		return zero, -1, false
+	}
+	return items[i], i, true
+}
+
+// listing is the JSON shape of every paginated `*GetOK` response.
+type listing[T any] struct {
+	Items    []T     `json:"items"`
+	Count    int     `json:"count"`
+	Next     *string `json:"next"`
+	Previous *string `json:"previous"`
+}
+
+// paginate slices items according to the page and per_page query parameters.
+func paginate[T any](r *http.Request, items []T) *listing[T] {
+	q := r.URL.Query()
+	page, err := strconv.Atoi(q.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	per, err := strconv.Atoi(q.Get("per_page"))
+	if err != nil || per < 1 {
+		per = DefaultPerPage
+	}
+
+	ret := &listing[T]{Items: []T{}, Count: len(items)}
+	if lo := (page - 1) * per; lo < len(items) {
+		ret.Items = items[lo:min(lo+per, len(items))]
+	}
+	if page*per < len(items) {
+		ret.Next = pageURL(r, page+1)
+	}
+	if page > 1 {
+		ret.Previous = pageURL(r, page-1)
+	}
+	return ret
+}
+
+func pageURL(r *http.Request, page int) *string {
+	u := url.URL{Scheme: "http", Host: r.Host, Path: r.URL.Path}
+	q := r.URL.Query()
+	q.Set("page", strconv.Itoa(page))
+	u.RawQuery = q.Encode()
+	ret := u.String()
+	return &ret
+}
+
+// order sorts a copy of items when the ordering query parameter is key or
+// -key.
+func order[T any](r *http.Request, items []T, key string, get func(T) string) []T {
+	items = slices.Clone(items)
+	cmp := func(a, b T) int { return strings.Compare(get(a), get(b)) }
+	switch r.URL.Query().Get("ordering") {
+	case key:
+		slices.SortStableFunc(items, cmp)
+	case "-" + key:
+		slices.SortStableFunc(items, func(a, b T) int { return cmp(b, a) })
+	}
+	return items
+}
+
+// filter keeps the items satisfying keep.
+func filter[T any](items []T, keep func(T) bool) []T {
+	ret := make([]T, 0, len(items))
+	for _, i := range items {
+		if keep(i) {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+func queryInt(r *http.Request, name string) (int, bool) {
+	n, err := strconv.Atoi(r.URL.Query().Get(name))
+	return n, err == nil
+}
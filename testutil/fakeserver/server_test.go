@@ -0,0 +1,418 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sacloud/iam-api-go"
+	"github.com/sacloud/iam-api-go/apis/folder"
+	"github.com/sacloud/iam-api-go/apis/group"
+	"github.com/sacloud/iam-api-go/apis/organization"
+	"github.com/sacloud/iam-api-go/apis/project"
+	"github.com/sacloud/iam-api-go/apis/projectapikey"
+	"github.com/sacloud/iam-api-go/apis/scim"
+	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
+	"github.com/sacloud/iam-api-go/apis/sso"
+	"github.com/sacloud/iam-api-go/apis/user"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func binding(role string, kind string, id int) v1.IamPolicy {
+	return v1.IamPolicy{
+		Role: v1.NewOptIamPolicyRole(v1.IamPolicyRole{
+			Type: v1.NewOptIamPolicyRoleType(v1.IamPolicyRoleTypePreset),
+			ID:   v1.NewOptString(role),
+		}),
+		Principals: []v1.Principal{{Type: v1.NewOptString(kind), ID: v1.NewOptInt(id)}},
+	}
+}
+
+func TestProjectPolicy(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	f, err := iam.NewFolderOp(client).Create(ctx, folder.CreateParams{Name: "folder"})
+	assert.NoError(err)
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project", ParentFolderID: &f.ID})
+	assert.NoError(err)
+	assert.Equal(f.ID, p.GetParentFolderID().Value)
+	u, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "user", Code: "user", Password: "password"})
+	assert.NoError(err)
+
+	api := iam.NewIAMPolicyOp(client)
+	expected := []v1.IamPolicy{binding("admin", "user", u.ID)}
+	_, err = api.UpdateProjectPolicy(ctx, p.ID, expected)
+	assert.NoError(err)
+
+	actual, err := api.ReadProjectPolicy(ctx, p.ID)
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+
+	empty, err := api.ReadFolderPolicy(ctx, f.ID)
+	assert.NoError(err)
+	assert.Empty(empty)
+
+	_, err = api.UpdateProjectPolicy(ctx, p.ID, []v1.IamPolicy{binding("owner", "user", u.ID)})
+	assert.True(iam.IsValidation(err))
+
+	_, err = api.UpdateProjectPolicy(ctx, p.ID, []v1.IamPolicy{binding("admin", "user", u.ID+1)})
+	assert.True(iam.IsValidation(err))
+
+	// deleting the user unbinds it
+	assert.NoError(iam.NewUserOp(client).Delete(ctx, u.ID))
+	actual, err = api.ReadProjectPolicy(ctx, p.ID)
+	assert.NoError(err)
+	assert.Empty(actual)
+}
+
+func TestNotFound(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+
+	_, err := iam.NewUserOp(client).Read(t.Context(), 1)
+	assert.True(iam.IsNotFound(err))
+
+	_, err = iam.NewGroupOp(client).ReadMemberships(t.Context(), 1)
+	assert.True(iam.IsNotFound(err))
+
+	_, err = iam.NewIAMPolicyOp(client).ReadProjectPolicy(t.Context(), 1)
+	assert.True(iam.IsNotFound(err))
+}
+
+func TestConflict(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	users := iam.NewUserOp(client)
+	params := user.CreateParams{Name: "user", Code: "user", Password: "password"}
+	_, err := users.Create(ctx, params)
+	assert.NoError(err)
+	_, err = users.Create(ctx, params)
+	assert.True(iam.IsConflict(err))
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	sp, err := iam.NewServicePrincipalOp(client).Create(ctx, serviceprincipal.CreateParams{ProjectID: p.ID, Name: "sp"})
+	assert.NoError(err)
+
+	err = iam.NewProjectOp(client).Delete(ctx, p.ID)
+	assert.True(iam.IsConflict(err))
+
+	assert.NoError(iam.NewServicePrincipalOp(client).Delete(ctx, sp.ID))
+	assert.NoError(iam.NewProjectOp(client).Delete(ctx, p.ID))
+}
+
+func TestHierarchy(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewFolderOp(client)
+
+	parent, err := api.Create(ctx, folder.CreateParams{Name: "parent"})
+	assert.NoError(err)
+	child, err := api.Create(ctx, folder.CreateParams{Name: "child", ParentID: &parent.ID})
+	assert.NoError(err)
+
+	_, err = api.Create(ctx, folder.CreateParams{Name: "child", ParentID: &parent.ID})
+	assert.True(iam.IsValidation(err))
+
+	err = api.Move(ctx, []int{parent.ID}, &child.ID)
+	assert.True(iam.IsValidation(err))
+
+	err = api.Delete(ctx, parent.ID)
+	assert.True(iam.IsValidation(err))
+	problem, ok := iam.AsProblem(err)
+	assert.True(ok)
+	assert.Equal("exist_dependency_folders_or_projects", problem.Title)
+
+	assert.NoError(api.Move(ctx, []int{child.ID}, nil))
+	moved, err := api.Read(ctx, child.ID)
+	assert.NoError(err)
+	assert.True(moved.GetParentID().Null)
+	assert.NoError(api.Delete(ctx, parent.ID))
+}
+
+func TestPagination(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	api := iam.NewGroupOp(client)
+
+	for i := range 25 {
+		_, err := api.Create(t.Context(), fmt.Sprintf("group%02d", i), "")
+		assert.NoError(err)
+	}
+
+	first, err := api.List(t.Context(), group.ListParams{})
+	assert.NoError(err)
+	assert.Equal(25, first.Count)
+	assert.Len(first.Items, 20)
+	assert.False(first.Next.Null)
+
+	all, err := group.ListAll(t.Context(), api, group.ListParams{})
+	assert.NoError(err)
+	assert.Len(all, 25)
+}
+
+func TestMemberships(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	u, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "user", Code: "user", Password: "password"})
+	assert.NoError(err)
+	g, err := iam.NewGroupOp(client).Create(ctx, "group", "")
+	assert.NoError(err)
+
+	members, err := iam.NewGroupOp(client).UpdateMemberships(ctx, g.ID, []int{u.ID})
+	assert.NoError(err)
+	assert.Equal([]v1.GroupMembershipsCompatUsersItem{{ID: u.ID}}, members)
+
+	groups, err := iam.NewGroupOp(client).List(ctx, group.ListParams{User: u})
+	assert.NoError(err)
+	assert.Len(groups.Items, 1)
+
+	_, err = iam.NewGroupOp(client).UpdateMemberships(ctx, g.ID, []int{u.ID + 1})
+	assert.True(iam.IsValidation(err))
+}
+
+func TestServicePrincipalToken(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewServicePrincipalOp(client)
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+	sp, err := api.Create(ctx, serviceprincipal.CreateParams{ProjectID: p.ID, Name: "sp"})
+	assert.NoError(err)
+
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(err)
+	der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+	assert.NoError(err)
+	pub := v1.ServiceprincipalKeyPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	key, err := api.UploadKey(ctx, sp.ID, pub)
+	assert.NoError(err)
+	assert.Equal(v1.ServicePrincipalKeyStatusEnabled, key.Status)
+
+	claims := jwt.MapClaims{
+		"iss": fmt.Sprint(sp.ID),
+		"sub": fmt.Sprint(sp.ID),
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.Kid
+	assertion, err := token.SignedString(k)
+	assert.NoError(err)
+
+	issued, err := api.IssueToken(ctx, assertion)
+	assert.NoError(err)
+	assert.NotEmpty(issued.AccessToken)
+
+	_, err = api.DisableKey(ctx, sp.ID, key.ID)
+	assert.NoError(err)
+	_, err = api.IssueToken(ctx, assertion)
+	assert.True(iam.IsUnauthorized(err))
+
+	err = api.Delete(ctx, sp.ID)
+	assert.True(iam.IsConflict(err))
+	assert.NoError(api.DeleteKey(ctx, sp.ID, key.ID))
+	assert.NoError(api.Delete(ctx, sp.ID))
+}
+
+func TestSSO(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewSSOOp(client)
+
+	a, err := api.Create(ctx, sso.CreateParams{Name: "a"})
+	assert.NoError(err)
+	b, err := api.Create(ctx, sso.CreateParams{Name: "b"})
+	assert.NoError(err)
+
+	linked, err := api.Link(ctx, a.ID)
+	assert.NoError(err)
+	assert.True(linked.Assigned)
+
+	_, err = api.Link(ctx, b.ID)
+	assert.True(iam.IsConflict(err))
+	assert.True(iam.IsConflict(api.Delete(ctx, a.ID)))
+
+	_, err = api.Unlink(ctx, a.ID)
+	assert.NoError(err)
+	assert.NoError(api.Delete(ctx, a.ID))
+}
+
+func TestScim(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewScimOp(client)
+
+	created, err := api.Create(ctx, scim.CreateParams{Name: "scim"})
+	assert.NoError(err)
+	assert.NotEmpty(created.SecretToken)
+
+	_, err = api.Create(ctx, scim.CreateParams{Name: "again"})
+	assert.True(iam.IsConflict(err))
+
+	regenerated, err := api.RegenerateToken(ctx, created.ID.String())
+	assert.NoError(err)
+	assert.NotEqual(created.SecretToken, regenerated.SecretToken.Value)
+
+	assert.NoError(api.Delete(ctx, created.ID.String()))
+	_, err = api.Read(ctx, created.ID.String())
+	assert.True(iam.IsNotFound(err))
+}
+
+func TestOrganization(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	org, err := iam.NewOrganizationOp(client).Update(ctx, "renamed")
+	assert.NoError(err)
+	assert.Equal("renamed", org.Name)
+
+	auth := iam.NewAuthOp(client)
+	conditions, err := auth.ReadAuthConditions(ctx)
+	assert.NoError(err)
+	conditions.RequireTwoFactorAuth.Enabled = true
+	_, err = auth.UpdateAuthConditions(ctx, conditions)
+	assert.NoError(err)
+	conditions, err = auth.ReadAuthConditions(ctx)
+	assert.NoError(err)
+	assert.True(conditions.RequireTwoFactorAuth.Enabled)
+
+	_, err = auth.UpdatePasswordPolicy(ctx, v1.PasswordPolicy{MinLength: 12, RequireSymbols: true})
+	assert.NoError(err)
+	_, err = iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "user", Code: "user", Password: "password1234"})
+	assert.True(iam.IsValidation(err))
+}
+
+func TestServicePolicy(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewServicePolicyOp(client)
+	rules := []v1.Rule{{Code: v1.NewOptString("allowed-zones"), IsActive: v1.NewOptBool(true)}}
+
+	_, err := iam.NewOrganizationOp(client).UpdateServicePolicy(ctx, rules)
+	assert.True(iam.IsConflict(err))
+
+	assert.NoError(api.Enable(ctx))
+	assert.True(iam.IsConflict(api.Enable(ctx)))
+	enabled, err := api.IsEnabled(ctx)
+	assert.NoError(err)
+	assert.True(enabled)
+
+	_, err = iam.NewOrganizationOp(client).UpdateServicePolicy(ctx, rules)
+	assert.NoError(err)
+	actual, err := iam.NewOrganizationOp(client).ReadServicePolicy(ctx, organization.GetServicePolicyParams{})
+	assert.NoError(err)
+	assert.Len(actual, 1)
+	assert.NotEmpty(actual[0].Name.Value)
+}
+
+func TestIDPolicy(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	g, err := iam.NewGroupOp(client).Create(ctx, "group", "")
+	assert.NoError(err)
+	expected := []v1.IdPolicy{{
+		Role: v1.NewOptIdPolicyRole(v1.IdPolicyRole{
+			Type: v1.NewOptIdPolicyRoleType(v1.IdPolicyRoleTypePreset),
+			ID:   v1.NewOptString("identity-admin"),
+		}),
+		Principals: []v1.Principal{{Type: v1.NewOptString("group"), ID: v1.NewOptInt(g.ID)}},
+	}}
+
+	api := iam.NewIDPolicyOp(client)
+	_, err = api.UpdateOrganizationIdPolicy(ctx, expected)
+	assert.NoError(err)
+	actual, err := api.ReadOrganizationIdPolicy(ctx)
+	assert.NoError(err)
+	assert.Equal(expected, actual)
+}
+
+func TestProjectAPIKey(t *testing.T) {
+	assert := require.New(t)
+	client, _ := testutil.NewFakeClient(t)
+	ctx := t.Context()
+	api := iam.NewProjectAPIKeyOp(client)
+
+	p, err := iam.NewProjectOp(client).Create(ctx, project.CreateParams{Code: "code", Name: "project"})
+	assert.NoError(err)
+
+	_, err = api.Create(ctx, projectapikey.CreateParams{ProjectID: p.ID, Name: "key", IamRoles: []string{"nonexistent"}})
+	assert.True(iam.IsValidation(err))
+
+	created, err := api.Create(ctx, projectapikey.CreateParams{ProjectID: p.ID, Name: "key", IamRoles: []string{"admin"}})
+	assert.NoError(err)
+	assert.NotEmpty(created.AccessTokenSecret)
+
+	read, err := api.Read(ctx, created.ID)
+	assert.NoError(err)
+	assert.Equal(created.AccessToken, read.AccessToken)
+
+	assert.True(iam.IsConflict(iam.NewProjectOp(client).Delete(ctx, p.ID)))
+	assert.NoError(api.Delete(ctx, created.ID))
+	assert.NoError(iam.NewProjectOp(client).Delete(ctx, p.ID))
+}
+
+func TestUser2FA(t *testing.T) {
+	assert := require.New(t)
+	client, sv := testutil.NewFakeClient(t)
+	ctx := t.Context()
+
+	u, err := iam.NewUserOp(client).Create(ctx, user.CreateParams{Name: "user", Code: "user", Password: "password"})
+	assert.NoError(err)
+	_, err = sv.AddTrustedDevice(u.ID, "laptop")
+	assert.NoError(err)
+	key, err := sv.AddSecurityKey(u.ID, "yubikey")
+	assert.NoError(err)
+
+	api := iam.NewUser2FAOp(client, u)
+	devices, err := api.ListTrustedDevices(ctx)
+	assert.NoError(err)
+	assert.Len(devices.Items, 1)
+	assert.NoError(api.ClearTrustedDevices(ctx))
+	devices, err = api.ListTrustedDevices(ctx)
+	assert.NoError(err)
+	assert.Empty(devices.Items)
+
+	renamed, err := api.UpdateSecurityKey(ctx, key, "renamed")
+	assert.NoError(err)
+	assert.Equal("renamed", renamed.Name)
+	assert.NoError(api.DeleteSecurityKey(ctx, key))
+	assert.True(iam.IsNotFound(api.DeleteSecurityKey(ctx, key)))
+}
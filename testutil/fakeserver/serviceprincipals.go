@@ -0,0 +1,237 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+// TokenLifetime is how long access tokens issued by the fake stay valid.
+const TokenLifetime = time.Hour
+
+func (s *Server) servicePrincipalRoutes() {
+	s.handle("GET /service-principals", func(w http.ResponseWriter, r *http.Request) {
+		items := s.servicePrincipals
+		if p, ok := queryInt(r, "project_id"); ok {
+			items = filter(items, func(sp *v1.ServicePrincipal) bool { return sp.ProjectID == p })
+		}
+		items = order(r, items, "name", func(sp *v1.ServicePrincipal) string { return sp.Name })
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /service-principals", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.ServicePrincipalsPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if indexOf(s.projects, req.ProjectID) < 0 {
+			invalid(w, "project_id", "project %d not found", req.ProjectID)
+			return
+		}
+		if s.servicePrincipalNameTaken(req.ProjectID, req.Name, 0) {
+			fail(w, http.StatusConflict, "service principal name %q is already taken", req.Name)
+			return
+		}
+
+		t := now()
+		sp := &v1.ServicePrincipal{
+			ID:          s.nextID(),
+			ProjectID:   req.ProjectID,
+			Name:        req.Name,
+			Description: req.Description,
+			CreatedAt:   v1.NewOptString(t),
+			UpdatedAt:   v1.NewOptString(t),
+		}
+		s.servicePrincipals = append(s.servicePrincipals, sp)
+		reply(w, http.StatusCreated, sp)
+	})
+
+	s.handle("GET /service-principals/{service_principal_id}", func(w http.ResponseWriter, r *http.Request) {
+		if sp, _, ok := lookup(w, r, "service_principal_id", s.servicePrincipals); ok {
+			reply(w, http.StatusOK, sp)
+		}
+	})
+	s.handle("PUT /service-principals/{service_principal_id}", func(w http.ResponseWriter, r *http.Request) {
+		sp, _, ok := lookup(w, r, "service_principal_id", s.servicePrincipals)
+		if !ok {
+			return
+		}
+		var req v1.ServicePrincipalsServicePrincipalIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if s.servicePrincipalNameTaken(sp.ProjectID, req.Name, sp.ID) {
+			invalid(w, "name", "service principal name %q is already taken", req.Name)
+			return
+		}
+		sp.Name = req.Name
+		if req.Description.Set {
+			sp.Description = req.Description.Value
+		}
+		sp.UpdatedAt = v1.NewOptString(now())
+		reply(w, http.StatusOK, sp)
+	})
+	s.handle("DELETE /service-principals/{service_principal_id}", func(w http.ResponseWriter, r *http.Request) {
+		sp, i, ok := lookup(w, r, "service_principal_id", s.servicePrincipals)
+		if !ok {
+			return
+		}
+		if len(s.keys[sp.ID]) > 0 {
+			fail(w, http.StatusConflict, "service principal %d still has keys", sp.ID)
+			return
+		}
+		s.servicePrincipals = slices.Delete(s.servicePrincipals, i, i+1)
+		delete(s.keys, sp.ID)
+		s.forgetPrincipal("service-principal", sp.ID)
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("GET /service-principals/{service_principal_id}/keys", func(w http.ResponseWriter, r *http.Request) {
+		if sp, _, ok := lookup(w, r, "service_principal_id", s.servicePrincipals); ok {
+			reply(w, http.StatusOK, paginate(r, s.keys[sp.ID]))
+		}
+	})
+	s.handle("POST /service-principals/{service_principal_id}/upload-key", func(w http.ResponseWriter, r *http.Request) {
+		sp, _, ok := lookup(w, r, "service_principal_id", s.servicePrincipals)
+		if !ok {
+			return
+		}
+		var req v1.ServicePrincipalsServicePrincipalIDUploadKeyPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if _, err := jwt.ParseRSAPublicKeyFromPEM([]byte(req.PublicKey)); err != nil {
+			invalid(w, "public_key", "%s", err)
+			return
+		}
+		if len(s.keys[sp.ID]) >= s.KeysPerServicePrincipal {
+			fail(w, http.StatusConflict, "service principal %d already has %d keys", sp.ID, s.KeysPerServicePrincipal)
+			return
+		}
+
+		k := &v1.ServicePrincipalKey{
+			ID:           uuid.New(),
+			Kid:          randomHex(16),
+			Status:       v1.ServicePrincipalKeyStatusEnabled,
+			KeyOrigin:    v1.ServicePrincipalKeyKeyOriginUser,
+			PublicKey:    req.PublicKey,
+			CreatedAt:    now(),
+			KeyExpiresAt: v1.OptNilString{Set: true, Null: true},
+		}
+		s.keys[sp.ID] = append(s.keys[sp.ID], k)
+		reply(w, http.StatusCreated, k)
+	})
+	s.handle("POST /service-principals/{service_principal_id}/keys/{service_principal_key_id}/enable", func(w http.ResponseWriter, r *http.Request) {
+		if k, _, ok := s.lookupKey(w, r); ok {
+			k.Status = v1.ServicePrincipalKeyStatusEnabled
+			reply(w, http.StatusOK, k)
+		}
+	})
+	s.handle("POST /service-principals/{service_principal_id}/keys/{service_principal_key_id}/disable", func(w http.ResponseWriter, r *http.Request) {
+		if k, _, ok := s.lookupKey(w, r); ok {
+			k.Status = v1.ServicePrincipalKeyStatusDisabled
+			reply(w, http.StatusOK, k)
+		}
+	})
+	s.handle("DELETE /service-principals/{service_principal_id}/keys/{service_principal_key_id}", func(w http.ResponseWriter, r *http.Request) {
+		if k, sp, ok := s.lookupKey(w, r); ok {
+			s.keys[sp] = slices.DeleteFunc(s.keys[sp], func(i *v1.ServicePrincipalKey) bool { return i == k })
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+
+	s.handle("POST /service-principals/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			invalid(w, "assertion", "%s", err)
+			return
+		}
+		if g := r.PostForm.Get("grant_type"); g != string(v1.ServicePrincipalJWTGrantRequestGrantTypeUrnIetfParamsOAuthGrantTypeJwtBearer) {
+			invalid(w, "grant_type", "unsupported grant type %q", g)
+			return
+		}
+		if _, err := jwt.Parse(r.PostForm.Get("assertion"), s.verificationKey,
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		); err != nil {
+			fail(w, http.StatusUnauthorized, "invalid assertion: %s", err)
+			return
+		}
+
+		reply(w, http.StatusOK, &v1.ServicePrincipalOAuth2AccessToken{
+			AccessToken:    randomHex(32),
+			TokenType:      v1.NewOptString("Bearer"),
+			TokenExpiredAt: time.Now().Add(TokenLifetime).UTC().Truncate(time.Second),
+			ExpiresIn:      v1.NewOptInt(int(TokenLifetime.Seconds())),
+		})
+	})
+}
+
+func (s *Server) servicePrincipalNameTaken(project int, name string, except int) bool {
+	return slices.ContainsFunc(s.servicePrincipals, func(sp *v1.ServicePrincipal) bool {
+		return sp.ID != except && sp.ProjectID == project && sp.Name == name
+	})
+}
+
+func (s *Server) lookupKey(w http.ResponseWriter, r *http.Request) (*v1.ServicePrincipalKey, int, bool) {
+	sp, _, ok := lookup(w, r, "service_principal_id", s.servicePrincipals)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := uuid.Parse(r.PathValue("service_principal_key_id"))
+	if err == nil {
+		if i := slices.IndexFunc(s.keys[sp.ID], func(k *v1.ServicePrincipalKey) bool { return k.ID == id }); i >= 0 {
+			return s.keys[sp.ID][i], sp.ID, true
+		}
+	}
+	fail(w, http.StatusNotFound, "service principal key %q not found", r.PathValue("service_principal_key_id"))
+	return nil, 0, false
+}
+
+// verificationKey finds the public half of the key that signed an assertion:
+// the enabled key of the service principal named by "sub" whose kid matches.
+func (s *Server) verificationKey(t *jwt.Token) (any, error) {
+	sub, err := t.Claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+	sp, err := strconv.Atoi(sub)
+	if err != nil {
+		return nil, fmt.Errorf("malformed subject %q", sub)
+	}
+	kid, _ := t.Header["kid"].(string)
+	for _, k := range s.keys[sp] {
+		if k.Kid == kid && k.Status == v1.ServicePrincipalKeyStatusEnabled {
+			return jwt.ParseRSAPublicKeyFromPEM([]byte(k.PublicKey))
+		}
+	}
+	return nil, errors.New("no such key")
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+func (s *Server) ssoRoutes() {
+	s.handle("GET /sso-profiles", func(w http.ResponseWriter, r *http.Request) {
+		reply(w, http.StatusOK, paginate(r, s.ssoProfiles))
+	})
+	s.handle("POST /sso-profiles", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.SSOProfilesPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if slices.ContainsFunc(s.ssoProfiles, func(p *v1.SSOProfile) bool { return p.Name == req.Name }) {
+			fail(w, http.StatusConflict, "SSO profile name %q is already taken", req.Name)
+			return
+		}
+
+		t := now()
+		id := s.nextID()
+		p := &v1.SSOProfile{
+			ID:             id,
+			Name:           req.Name,
+			Description:    req.Description,
+			SpEntityID:     fmt.Sprintf("%s/sso/%d", s.URL, id),
+			SpAcsURL:       fmt.Sprintf("%s/sso/%d/acs", s.URL, id),
+			IdpEntityID:    req.IdpEntityID,
+			IdpLoginURL:    req.IdpLoginURL,
+			IdpLogoutURL:   req.IdpLogoutURL,
+			IdpCertificate: req.IdpCertificate,
+			CreatedAt:      t,
+			UpdatedAt:      t,
+		}
+		s.ssoProfiles = append(s.ssoProfiles, p)
+		reply(w, http.StatusCreated, p)
+	})
+
+	s.handle("GET /sso-profiles/{sso_profile_id}", func(w http.ResponseWriter, r *http.Request) {
+		if p, _, ok := lookup(w, r, "sso_profile_id", s.ssoProfiles); ok {
+			reply(w, http.StatusOK, p)
+		}
+	})
+	s.handle("PUT /sso-profiles/{sso_profile_id}", func(w http.ResponseWriter, r *http.Request) {
+		p, _, ok := lookup(w, r, "sso_profile_id", s.ssoProfiles)
+		if !ok {
+			return
+		}
+		var req v1.SSOProfilesSSOProfileIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		p.Name = req.Name
+		p.Description = req.Description
+		p.IdpEntityID = req.IdpEntityID
+		p.IdpLoginURL = req.IdpLoginURL
+		p.IdpLogoutURL = req.IdpLogoutURL
+		p.IdpCertificate = req.IdpCertificate
+		p.UpdatedAt = now()
+		reply(w, http.StatusOK, p)
+	})
+	s.handle("DELETE /sso-profiles/{sso_profile_id}", func(w http.ResponseWriter, r *http.Request) {
+		p, i, ok := lookup(w, r, "sso_profile_id", s.ssoProfiles)
+		if !ok {
+			return
+		}
+		if p.Assigned {
+			fail(w, http.StatusConflict, "SSO profile %d is assigned", p.ID)
+			return
+		}
+		s.ssoProfiles = slices.Delete(s.ssoProfiles, i, i+1)
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("POST /sso-profiles/{sso_profile_id}/assign", func(w http.ResponseWriter, r *http.Request) {
+		p, _, ok := lookup(w, r, "sso_profile_id", s.ssoProfiles)
+		if !ok {
+			return
+		}
+		if slices.ContainsFunc(s.ssoProfiles, func(i *v1.SSOProfile) bool { return i.Assigned && i != p }) {
+			fail(w, http.StatusConflict, "another SSO profile is already assigned")
+			return
+		}
+		p.Assigned = true
+		reply(w, http.StatusOK, p)
+	})
+	s.handle("POST /sso-profiles/{sso_profile_id}/unassign", func(w http.ResponseWriter, r *http.Request) {
+		if p, _, ok := lookup(w, r, "sso_profile_id", s.ssoProfiles); ok {
+			p.Assigned = false
+			reply(w, http.StatusOK, p)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+// Copyright 2025- The sacloud/iam-api-go Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeserver
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	v1 "github.com/sacloud/iam-api-go/apis/v1"
+)
+
+type user struct {
+	v1.User
+	password       string
+	trustedDevices []*v1.UserTrustedDevice
+	securityKeys   []*v1.UserSecurityKey
+}
+
+// AddTrustedDevice registers a trusted device for a user, as a browser would
+// after a successful two-factor login.
+func (s *Server) AddTrustedDevice(userID int, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := indexOf(s.users, userID)
+	if i < 0 {
+		return 0, fmt.Errorf("user %d not found", userID)
+	}
+	d := &v1.UserTrustedDevice{ID: s.nextID(), Name: name, CreatedAt: time.Now().UTC()}
+	s.users[i].trustedDevices = append(s.users[i].trustedDevices, d)
+	return d.ID, nil
+}
+
+// AddSecurityKey registers a WebAuthn security key for a user.
+func (s *Server) AddSecurityKey(userID int, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := indexOf(s.users, userID)
+	if i < 0 {
+		return 0, fmt.Errorf("user %d not found", userID)
+	}
+	k := &v1.UserSecurityKey{
+		ID:           s.nextID(),
+		Name:         name,
+		Aaguid:       "00000000-0000-0000-0000-000000000000",
+		RegisteredAt: time.Now().UTC(),
+		LastUsedAt:   v1.NilDateTime{Null: true},
+	}
+	u := s.users[i]
+	u.securityKeys = append(u.securityKeys, k)
+	u.IsSecurityKeyRegistered = true
+	return k.ID, nil
+}
+
+func (s *Server) userRoutes() {
+	s.handle("GET /compat/users", func(w http.ResponseWriter, r *http.Request) {
+		items := order(r, s.users, "code", func(u *user) string { return u.Code })
+		reply(w, http.StatusOK, paginate(r, items))
+	})
+	s.handle("POST /compat/users", func(w http.ResponseWriter, r *http.Request) {
+		var req v1.CompatUsersPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if slices.ContainsFunc(s.users, func(u *user) bool { return u.Code == req.Code }) {
+			fail(w, http.StatusConflict, "user code %q is already taken", req.Code)
+			return
+		}
+		if req.Email.Set && s.emailTaken(req.Email.Value, 0) {
+			fail(w, http.StatusConflict, "email %q is already registered", req.Email.Value)
+			return
+		}
+		if !s.checkPassword(w, req.Password) {
+			return
+		}
+
+		t := now()
+		u := &user{
+			User: v1.User{
+				ID:          s.nextID(),
+				Member:      v1.UserMember{ID: s.organization.ID.Value, Code: "fake-member"},
+				Name:        req.Name,
+				Code:        req.Code,
+				Status:      v1.UserStatusAvailable,
+				Description: req.Description,
+				Otp:         v1.UserOtp{Status: v1.UserOtpStatusDeactivated},
+				Email:       req.Email.Value,
+				CreatedAt:   t,
+				UpdatedAt:   t,
+			},
+			password: req.Password,
+		}
+		s.users = append(s.users, u)
+		reply(w, http.StatusCreated, u)
+	})
+
+	s.handle("GET /compat/users/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			reply(w, http.StatusOK, u)
+		}
+	})
+	s.handle("PUT /compat/users/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		var req v1.CompatUsersUserIDPutReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if req.Password.Set {
+			if !s.checkPassword(w, req.Password.Value) {
+				return
+			}
+			u.password = req.Password.Value
+		}
+		u.Name = req.Name
+		u.Description = req.Description
+		u.UpdatedAt = now()
+		reply(w, http.StatusOK, u)
+	})
+	s.handle("DELETE /compat/users/{user_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, i, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		s.users = slices.Delete(s.users, i, i+1)
+		for g, m := range s.memberships {
+			s.memberships[g] = slices.DeleteFunc(m, func(id int) bool { return id == u.ID })
+		}
+		s.forgetPrincipal("user", u.ID)
+		reply(w, http.StatusNoContent, nil)
+	})
+
+	s.handle("POST /compat/users/{user_id}/deactivate-otp", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			u.Otp = v1.UserOtp{Status: v1.UserOtpStatusDeactivated}
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+
+	s.handle("POST /compat/users/{user_id}/register-email", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		var req v1.CompatUsersUserIDRegisterEmailPostReq
+		if !decode(w, r, &req) {
+			return
+		}
+		if s.emailTaken(req.Email, u.ID) {
+			fail(w, http.StatusConflict, "email %q is already registered", req.Email)
+			return
+		}
+		u.Email = req.Email
+		reply(w, http.StatusNoContent, nil)
+	})
+	s.handle("POST /compat/users/{user_id}/unregister-email", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			u.Email = ""
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+
+	s.handle("GET /compat/users/{user_id}/trusted-devices", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			reply(w, http.StatusOK, paginate(r, u.trustedDevices))
+		}
+	})
+	s.handle("DELETE /compat/users/{user_id}/trusted-devices/{trusted_device_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		if _, i, ok := lookup(w, r, "trusted_device_id", u.trustedDevices); ok {
+			u.trustedDevices = slices.Delete(u.trustedDevices, i, i+1)
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+	s.handle("POST /compat/users/{user_id}/clear-trusted-devices", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			u.trustedDevices = nil
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+
+	s.handle("GET /compat/users/{user_id}/security-keys", func(w http.ResponseWriter, r *http.Request) {
+		if u, _, ok := lookup(w, r, "user_id", s.users); ok {
+			reply(w, http.StatusOK, paginate(r, u.securityKeys))
+		}
+	})
+	s.handle("GET /compat/users/{user_id}/security-keys/{security_key_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		if k, _, ok := lookup(w, r, "security_key_id", u.securityKeys); ok {
+			reply(w, http.StatusOK, k)
+		}
+	})
+	s.handle("PUT /compat/users/{user_id}/security-keys/{security_key_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		k, _, ok := lookup(w, r, "security_key_id", u.securityKeys)
+		if !ok {
+			return
+		}
+		var req v1.CompatUsersUserIDSecurityKeysSecurityKeyIDPutReq
+		if r.ContentLength != 0 && !decode(w, r, &req) {
+			return
+		}
+		if req.Name != "" {
+			k.Name = req.Name
+		}
+		reply(w, http.StatusOK, k)
+	})
+	s.handle("DELETE /compat/users/{user_id}/security-keys/{security_key_id}", func(w http.ResponseWriter, r *http.Request) {
+		u, _, ok := lookup(w, r, "user_id", s.users)
+		if !ok {
+			return
+		}
+		if _, i, ok := lookup(w, r, "security_key_id", u.securityKeys); ok {
+			u.securityKeys = slices.Delete(u.securityKeys, i, i+1)
+			u.IsSecurityKeyRegistered = len(u.securityKeys) > 0
+			reply(w, http.StatusNoContent, nil)
+		}
+	})
+}
+
+func (s *Server) emailTaken(email string, except int) bool {
+	return slices.ContainsFunc(s.users, func(u *user) bool {
+		return u.ID != except && strings.EqualFold(u.Email, email)
+	})
+}
+
+// checkPassword enforces the organization password policy, answering 400 if
+// the password falls short.
+func (s *Server) checkPassword(w http.ResponseWriter, password string) bool {
+	p := s.passwordPolicy
+	switch {
+	case len(password) < p.MinLength:
+		invalid(w, "password", "must be at least %d characters", p.MinLength)
+	case p.RequireUppercase && !strings.ContainsFunc(password, unicode.IsUpper):
+		invalid(w, "password", "must contain an uppercase letter")
+	case p.RequireLowercase && !strings.ContainsFunc(password, unicode.IsLower):
+		invalid(w, "password", "must contain a lowercase letter")
+	case p.RequireSymbols && !strings.ContainsFunc(password, isSymbol):
+		invalid(w, "password", "must contain a symbol")
+	default:
+		return true
+	}
+	return false
+}
+
+func isSymbol(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
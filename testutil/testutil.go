@@ -29,6 +29,7 @@ import (
 	"github.com/sacloud/iam-api-go/apis/serviceprincipal"
 	"github.com/sacloud/iam-api-go/apis/user"
 	v1 "github.com/sacloud/iam-api-go/apis/v1"
+	"github.com/sacloud/iam-api-go/testutil/fakeserver"
 	super "github.com/sacloud/packages-go/testutil"
 	"github.com/sacloud/saclient-go"
 	"github.com/stretchr/testify/require"
@@ -70,6 +71,31 @@ func NewTestClient(v any, s ...int) *v1.Client {
 	return c
 }
 
+// NewFakeClient connects a client to a fresh fakeserver, which is shut down
+// when the test ends.
+func NewFakeClient(t *testing.T) (*v1.Client, *fakeserver.Server) {
+	sv := fakeserver.New()
+	t.Cleanup(sv.Close)
+
+	// No need to throttle ourselves against an in-process server.
+	var local saclient.Client
+	if err := local.SetEnviron([]string{"SAKURA_RATE_LIMIT=1000"}); err != nil {
+		t.Fatalf("saclient.SetEnviron() failed: %s", err)
+	}
+	sa, err := local.DupWith(saclient.WithTestServer(sv.Server))
+	if err != nil {
+		t.Fatalf("saclient.DupWith() failed: %s", err)
+	}
+	c, err := iam.NewClientWithAPIRootURL(sa, sv.URL)
+	if err != nil {
+		t.Fatalf("iam.NewClientWithAPIRootURL() failed: %s", err)
+	}
+	if err := sa.Populate(); err != nil {
+		t.Fatalf("saclient.Populate() failed: %s", err)
+	}
+	return c, sv
+}
+
 func IntegratedClient(
 	t *testing.T,
 ) (